@@ -23,7 +23,11 @@ package sweap
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/url"
+	"regexp"
+	"sort"
 	"time"
 )
 
@@ -44,36 +48,37 @@ func (api *Client) GetCategories(eventId string) (*Categories, error) {
 
 // GetCategories will retrieve the complete list of guest categories for a given eventId with a custom context
 func (api *Client) GetCategoriesContext(ctx context.Context, eventId string, params CategorySearchParameter) (*Categories, error) {
+	if eventId == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+
+	response, err := api.categoriesRequest(ctx, "categories", categorySearchValues(eventId, params))
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func categorySearchValues(eventId string, params CategorySearchParameter) url.Values {
 	values := url.Values{}
+	values.Add("eventId", eventId)
 
 	if params.GuestId != "" {
 		values.Add("id", params.GuestId)
 	}
 	if params.Name != "" {
-		values.Add("id", params.Name)
+		values.Add("name", params.Name)
 	}
-
 	if params.ExternalID != "" {
-		values.Add("external_id", params.ExternalID)
+		values.Add("externalId", params.ExternalID)
 	}
 	if params.CreatedAfter != nil {
-		values.Add("start_date_after", params.CreatedAfter.Format(time.RFC3339))
+		values.Add("createdAfter", params.CreatedAfter.Format(time.RFC3339))
 	}
-
 	if params.UpdatedAfter != nil {
-		values.Add("end_date_after", params.UpdatedAfter.Format(time.RFC3339))
+		values.Add("updatedAfter", params.UpdatedAfter.Format(time.RFC3339))
 	}
-
-	if eventId != "" {
-		values.Add("event_id", eventId)
-	} else {
-		panic("no event id given")
-	}
-	response, err := api.categoriesRequest(ctx, "categories", values)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	return values
 }
 
 // GetCategoryById will retrieve the guest with the given guestId
@@ -93,6 +98,156 @@ func (api *Client) GetCategoryByIdContext(ctx context.Context, categoryId string
 	return response, nil
 }
 
+// CreateCategory creates a guest category in an event and returns it containing it's unique ID
+// POST /categories
+func (api *Client) CreateCategory(c Category) (*Category, error) {
+	return api.CreateCategoryContext(context.Background(), c)
+}
+
+// CreateCategoryContext creates a guest category with a custom context. The category is validated before it is sent.
+func (api *Client) CreateCategoryContext(ctx context.Context, c Category) (*Category, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Category)
+	if err := postJSON(ctx, api.httpclient, api.endpoint+"categories", request, resp, api); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateCategory updates the category with the ID of the given category
+// PUT /categories/{ID}
+func (api *Client) UpdateCategory(c Category) (*Category, error) {
+	return api.UpdateCategoryContext(context.Background(), c)
+}
+
+// UpdateCategoryContext updates the category with the ID of the given category with a custom context.
+func (api *Client) UpdateCategoryContext(ctx context.Context, c Category) (*Category, error) {
+	if c.ID == "" {
+		return nil, SweapLibraryError{"no category ID given"}
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	request, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Category)
+	if err := putJSON(ctx, api.httpclient, api.endpoint+"categories/"+c.ID, request, resp, api); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteCategory will delete the category with the given ID. If category is not found error will be returned
+// DELETE /categories/{ID}
+func (api *Client) DeleteCategory(categoryId string) error {
+	return api.DeleteCategoryContext(context.Background(), categoryId)
+}
+
+// DeleteCategoryContext will delete the category with the given ID with a custom context.
+func (api *Client) DeleteCategoryContext(ctx context.Context, categoryId string) error {
+	if categoryId == "" {
+		return SweapLibraryError{"no category ID given"}
+	}
+
+	return deleteResource(ctx, api.httpclient, api.endpoint+"categories/"+categoryId, nil, api)
+}
+
+// ReorderCategories rewrites the SortIndex of all categories of an event.
+// The categories listed in categoryIds come first, in the given order; the remaining
+// categories follow in their previous order. Only categories whose SortIndex changes are updated.
+// The categories of the event are returned ordered by their new SortIndex.
+func (api *Client) ReorderCategories(eventId string, categoryIds []string) (Categories, error) {
+	return api.ReorderCategoriesContext(context.Background(), eventId, categoryIds)
+}
+
+// ReorderCategoriesContext rewrites the SortIndex of all categories of an event with a custom context.
+func (api *Client) ReorderCategoriesContext(ctx context.Context, eventId string, categoryIds []string) (Categories, error) {
+	current, err := api.GetCategoriesContext(ctx, eventId, NewCategorySearchParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	ordered, err := reorderCategories(*current, categoryIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range ordered {
+		if c.SortIndex == i {
+			continue
+		}
+		c.SortIndex = i
+		updated, err := api.UpdateCategoryContext(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		ordered[i] = *updated
+	}
+	return ordered, nil
+}
+
+// reorderCategories returns categories with the ones in ids first, followed by the
+// others ordered by SortIndex. It fails if ids contains unknown or duplicate IDs.
+func reorderCategories(categories Categories, ids []string) (Categories, error) {
+	rest := append(Categories(nil), categories...)
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].SortIndex < rest[j].SortIndex })
+
+	ordered := make(Categories, 0, len(categories))
+	for _, id := range ids {
+		i := indexOfCategory(rest, id)
+		if i < 0 {
+			if indexOfCategory(ordered, id) >= 0 {
+				return nil, SweapLibraryError{fmt.Sprintf("category %v given twice", id)}
+			}
+			return nil, SweapLibraryError{fmt.Sprintf("category %v does not belong to the event", id)}
+		}
+		ordered = append(ordered, rest[i])
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	return append(ordered, rest...), nil
+}
+
+func indexOfCategory(categories Categories, id string) int {
+	for i, c := range categories {
+		if c.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+var colorHex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Validate checks the category before it is created or updated: EventID and Name must be given
+// and ColorHex, if set, must be a hex color like #1a2b3c or #abc.
+func (c Category) Validate() error {
+	if c.EventID == "" {
+		return SweapLibraryError{"no event ID given in category"}
+	}
+	if c.Name == "" {
+		return SweapLibraryError{"no category name given"}
+	}
+	if c.ColorHex != "" && !colorHex.MatchString(c.ColorHex) {
+		return SweapLibraryError{fmt.Sprintf("invalid color %q, expected #rrggbb", c.ColorHex)}
+	}
+	if c.SortIndex < 0 {
+		return SweapLibraryError{"negative sort index"}
+	}
+	return nil
+}
+
 func (api *Client) categoriesRequest(ctx context.Context, path string, values url.Values) (*Categories, error) {
 	response := &Categories{}
 
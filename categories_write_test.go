@@ -0,0 +1,152 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func TestCategoryLifecycle(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Categories"}}})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	c, err := api.CreateCategory(sweap.Category{EventID: "event-1", Name: "VIP", ColorHex: "#ff8800"})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, c.ID)
+
+	c.ColorHex = "#0f0"
+	c, err = api.UpdateCategory(*c)
+	assert.Nil(t, err)
+	assert.Equal(t, "#0f0", srv.Categories("event-1")[0].ColorHex)
+
+	assert.Nil(t, api.DeleteCategory(c.ID))
+	assert.Empty(t, srv.Categories("event-1"))
+	assert.True(t, errors.Is(api.DeleteCategory(c.ID), sweap.ErrNotFound))
+}
+
+func TestCategoryValidation(t *testing.T) {
+	valid := sweap.Category{EventID: "event-1", Name: "Press", ColorHex: "#A1b2C3"}
+	assert.Nil(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(c *sweap.Category)
+	}{
+		{"no event", func(c *sweap.Category) { c.EventID = "" }},
+		{"no name", func(c *sweap.Category) { c.Name = "" }},
+		{"no hash", func(c *sweap.Category) { c.ColorHex = "a1b2c3" }},
+		{"not hex", func(c *sweap.Category) { c.ColorHex = "#a1b2cg" }},
+		{"wrong length", func(c *sweap.Category) { c.ColorHex = "#a1b2" }},
+		{"negative sort index", func(c *sweap.Category) { c.SortIndex = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := valid
+			tt.modify(&c)
+			assert.ErrorAs(t, c.Validate(), &sweap.SweapLibraryError{})
+		})
+	}
+
+	srv := sweaptest.NewServer(sweaptest.Seed{})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	_, err := api.CreateCategory(sweap.Category{EventID: "event-1", Name: "Red", ColorHex: "red"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	_, err = api.UpdateCategory(valid)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	_, err = api.GetCategories("")
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Empty(t, srv.Requests())
+}
+
+func TestCategorySearchParameters(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	since := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	_, err := api.GetCategoriesContext(context.Background(), "event-1", sweap.CategorySearchParameter{
+		GuestId: "category-1", Name: "VIP", ExternalID: "ext-1", CreatedAfter: &since, UpdatedAfter: &since,
+	})
+	assert.Nil(t, err)
+
+	q := srv.Requests()[0].Query
+	assert.Equal(t, "event-1", q.Get("eventId"))
+	assert.Equal(t, "category-1", q.Get("id"))
+	assert.Equal(t, "VIP", q.Get("name"))
+	assert.Equal(t, "ext-1", q.Get("externalId"))
+	assert.Equal(t, "2023-06-01T00:00:00Z", q.Get("createdAfter"))
+	assert.Equal(t, "2023-06-01T00:00:00Z", q.Get("updatedAfter"))
+}
+
+func TestReorderCategories(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Categories"}, {ID: "event-2", Name: "Other"}},
+		Categories: sweap.Categories{
+			{ID: "a", EventID: "event-1", Name: "A", SortIndex: 0},
+			{ID: "b", EventID: "event-1", Name: "B", SortIndex: 1},
+			{ID: "c", EventID: "event-1", Name: "C", SortIndex: 2},
+			{ID: "d", EventID: "event-1", Name: "D", SortIndex: 3},
+			{ID: "x", EventID: "event-2", Name: "X", SortIndex: 0},
+		},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	ordered, err := api.ReorderCategories("event-1", []string{"c", "a"})
+	assert.Nil(t, err)
+	ids := []string{}
+	for i, c := range ordered {
+		assert.Equal(t, i, c.SortIndex)
+		ids = append(ids, c.ID)
+	}
+	assert.Equal(t, []string{"c", "a", "b", "d"}, ids)
+
+	stored := map[string]int{}
+	for _, c := range srv.Categories("event-1") {
+		stored[c.ID] = c.SortIndex
+	}
+	assert.Equal(t, map[string]int{"c": 0, "a": 1, "b": 2, "d": 3}, stored)
+
+	puts := 0
+	for _, r := range srv.Requests() {
+		if r.Method == "PUT" {
+			puts++
+		}
+	}
+	assert.Equal(t, 3, puts) // d keeps its index
+
+	_, err = api.ReorderCategories("event-1", []string{"x"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	_, err = api.ReorderCategories("event-1", []string{"a", "a"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
@@ -0,0 +1,153 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// GuestVersion is the state of a guest recorded in a Checkpoint.
+type GuestVersion struct {
+	Version   int        `json:"version"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Checkpoint records what Listen has processed for an event, so that a restarted listener resumes where it left off.
+type Checkpoint struct {
+	EventID   string                  `json:"eventId"`
+	HighWater time.Time               `json:"highWater"` // latest updatedAt processed
+	Guests    map[string]GuestVersion `json:"guests"`    // known guests by ID
+}
+
+// CheckpointStore persists checkpoints of Listen. Load returns nil and no error if there is no checkpoint for the event.
+type CheckpointStore interface {
+	Load(eventID string) (*Checkpoint, error)
+	Save(cp Checkpoint) error
+}
+
+// ListenCheckpoints makes Listen resume from and record its progress in store.
+// Guests known from a checkpoint are not reported as NEW_GUEST again.
+func ListenCheckpoints(store CheckpointStore) func(*listenConfig) {
+	return func(c *listenConfig) {
+		c.checkpoints = store
+	}
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory. It is safe for concurrent use.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: make(map[string]Checkpoint)}
+}
+
+func (m *MemoryCheckpointStore) Load(eventID string) (*Checkpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cp, ok := m.checkpoints[eventID]
+	if !ok {
+		return nil, nil
+	}
+	cp.Guests = copyGuestVersions(cp.Guests)
+	return &cp, nil
+}
+
+func (m *MemoryCheckpointStore) Save(cp Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cp.Guests = copyGuestVersions(cp.Guests)
+	m.checkpoints[cp.EventID] = cp
+	return nil
+}
+
+// FileCheckpointStore keeps one JSON file per event in a directory.
+type FileCheckpointStore struct {
+	dir string
+}
+
+// NewFileCheckpointStore returns a store writing to dir, which is created if needed.
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileCheckpointStore{dir: dir}, nil
+}
+
+func (f *FileCheckpointStore) path(eventID string) string {
+	return filepath.Join(f.dir, "checkpoint-"+filepath.Base(eventID)+".json")
+}
+
+func (f *FileCheckpointStore) Load(eventID string) (*Checkpoint, error) {
+	data, err := os.ReadFile(f.path(eventID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &Checkpoint{}
+	if err := json.Unmarshal(data, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// Save writes the checkpoint to a temporary file first, so that a crash never leaves a partial checkpoint.
+func (f *FileCheckpointStore) Save(cp Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(f.dir, "checkpoint-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(cp.EventID))
+}
+
+func copyGuestVersions(m map[string]GuestVersion) map[string]GuestVersion {
+	c := make(map[string]GuestVersion, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
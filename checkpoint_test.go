@@ -0,0 +1,68 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCheckpointStore(t *testing.T, store CheckpointStore) {
+	cp, err := store.Load("event-1")
+	assert.Nil(t, err)
+	assert.Nil(t, cp)
+
+	updated := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	saved := Checkpoint{
+		EventID:   "event-1",
+		HighWater: updated,
+		Guests:    map[string]GuestVersion{"guest-1": {Version: 3, UpdatedAt: &updated}},
+	}
+	assert.Nil(t, store.Save(saved))
+
+	cp, err = store.Load("event-1")
+	assert.Nil(t, err)
+	assert.Equal(t, "event-1", cp.EventID)
+	assert.True(t, updated.Equal(cp.HighWater))
+	assert.Equal(t, 3, cp.Guests["guest-1"].Version)
+
+	saved.Guests["guest-2"] = GuestVersion{Version: 1}
+	assert.Nil(t, store.Save(saved))
+	cp, _ = store.Load("event-1")
+	assert.Equal(t, 2, len(cp.Guests))
+
+	cp, err = store.Load("event-2")
+	assert.Nil(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestMemoryCheckpointStore(t *testing.T) {
+	testCheckpointStore(t, NewMemoryCheckpointStore())
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	store, err := NewFileCheckpointStore(t.TempDir())
+	assert.Nil(t, err)
+	testCheckpointStore(t, store)
+}
@@ -0,0 +1,258 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// CloneOptions control what CloneEvent copies.
+type CloneOptions struct {
+	Name             string            // name of the new event, defaults to the name of the source event
+	Shift            time.Duration     // added to StartDate and EndDate of the new event
+	Guests           bool              // copy guests, otherwise only the event, its custom fields and categories
+	InvitationStates []InvitationState // copy only guests in one of these states, all if empty
+	CategoryIDs      []string          // copy only guests in one of these categories of the source event, all if empty
+	DryRun           bool              // only print the plan to Out, nothing is created
+	Out              io.Writer         // receives the plan of a dry run, defaults to os.Stdout
+}
+
+// CloneReport maps the IDs of the source event to the IDs of the clone.
+// In a dry run the new IDs are empty.
+type CloneReport struct {
+	SourceEventID string
+	Event         Event             // the created event, or the planned one in a dry run
+	CustomFields  map[string]string // custom field definition IDs, source to clone
+	Categories    map[string]string // category IDs, source to clone
+	Guests        map[string]string // guest IDs, source to clone
+	Orphans       []string          // source IDs of companions copied without their parent, which the filters left out
+	DryRun        bool
+}
+
+// CloneEvent copies an event with its custom field definitions, categories and optionally guests.
+// The new event starts as DRAFT. Copied guests are assigned to the copied categories and custom field
+// values are moved to the copied definitions; invitation, ticket and attendance are not copied.
+// Companions whose parent guest is not copied become guests of their own and are listed as Orphans.
+func (api *Client) CloneEvent(sourceEventId string, opts CloneOptions) (*CloneReport, error) {
+	return api.CloneEventContext(context.Background(), sourceEventId, opts)
+}
+
+// CloneEventContext copies an event with a custom context.
+// If copying fails halfway, the report of what has been created so far is returned with the error.
+func (api *Client) CloneEventContext(ctx context.Context, sourceEventId string, opts CloneOptions) (*CloneReport, error) {
+	if sourceEventId == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+
+	source, err := api.GetEventByIdContext(ctx, sourceEventId)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := api.GetCategoriesContext(ctx, sourceEventId, NewCategorySearchParameter())
+	if err != nil {
+		return nil, err
+	}
+	var guests Guests
+	if opts.Guests {
+		if guests, err = api.cloneableGuests(ctx, sourceEventId, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &CloneReport{
+		SourceEventID: sourceEventId,
+		Event:         cloneEvent(*source, opts),
+		CustomFields:  map[string]string{},
+		Categories:    map[string]string{},
+		Guests:        map[string]string{},
+		Orphans:       orphans(guests),
+		DryRun:        opts.DryRun,
+	}
+
+	if opts.DryRun {
+		for _, d := range source.CustomFieldDefinitions {
+			report.CustomFields[d.ID] = ""
+		}
+		for _, c := range *categories {
+			report.Categories[c.ID] = ""
+		}
+		for _, g := range guests {
+			report.Guests[g.ID] = ""
+		}
+		out := opts.Out
+		if out == nil {
+			out = os.Stdout
+		}
+		return report, report.writePlan(out, *source, *categories, guests)
+	}
+
+	created, err := api.CreateEventContext(ctx, report.Event)
+	if err != nil {
+		return report, err
+	}
+	report.Event = *created
+	for _, d := range source.CustomFieldDefinitions {
+		for _, n := range created.CustomFieldDefinitions {
+			if n.Name == d.Name {
+				report.CustomFields[d.ID] = n.ID
+			}
+		}
+	}
+
+	for _, c := range *categories {
+		c.EventID = created.ID
+		sourceID := c.ID
+		c.ID = ""
+		nc, err := api.CreateCategoryContext(ctx, c)
+		if err != nil {
+			return report, err
+		}
+		report.Categories[sourceID] = nc.ID
+	}
+
+	// parents are created before their entourage, so that ParentGuestID can be mapped
+	sort.SliceStable(guests, func(i, j int) bool { return guests[i].ParentGuestID == "" && guests[j].ParentGuestID != "" })
+	for _, g := range guests {
+		ng, err := api.CreateGuestContext(ctx, report.cloneGuest(g))
+		if err != nil {
+			return report, err
+		}
+		report.Guests[g.ID] = ng.ID
+	}
+
+	return report, nil
+}
+
+func (api *Client) cloneableGuests(ctx context.Context, eventId string, opts CloneOptions) (Guests, error) {
+	guests := Guests{}
+	it := api.IterateGuests(ctx, eventId, NewGuestSearchParameters())
+	for it.Next() {
+		g := it.Value()
+		if len(opts.InvitationStates) > 0 && !contains(opts.InvitationStates, g.InvitationState) {
+			continue
+		}
+		if len(opts.CategoryIDs) > 0 && !contains(opts.CategoryIDs, g.CategoryID) {
+			continue
+		}
+		guests = append(guests, g)
+	}
+	return guests, it.Err()
+}
+
+// orphans returns the IDs of the companions among the guests whose parent is not among them.
+func orphans(guests Guests) []string {
+	ids := map[string]bool{}
+	for _, g := range guests {
+		ids[g.ID] = true
+	}
+	var orphans []string
+	for _, g := range guests {
+		if g.ParentGuestID != "" && !ids[g.ParentGuestID] {
+			orphans = append(orphans, g.ID)
+		}
+	}
+	return orphans
+}
+
+func contains[T comparable](values []T, v T) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func cloneEvent(e Event, opts CloneOptions) Event {
+	e.ID, e.Version, e.CreatedAt, e.UpdatedAt, e.ExternalID = "", 0, nil, nil, nil
+	if opts.Name != "" {
+		e.Name = opts.Name
+	}
+	if !e.StartDate.IsZero() {
+		e.StartDate = e.StartDate.Add(opts.Shift)
+	}
+	if !e.EndDate.IsZero() {
+		e.EndDate = e.EndDate.Add(opts.Shift)
+	}
+	e.State = string(DRAFT)
+
+	defs := make([]CustomFieldDefinitions, len(e.CustomFieldDefinitions))
+	for i, d := range e.CustomFieldDefinitions {
+		d.ID = ""
+		defs[i] = d
+	}
+	e.CustomFieldDefinitions = defs
+	return e
+}
+
+// cloneGuest prepares a copy of g for the cloned event, using the IDs created so far.
+func (r *CloneReport) cloneGuest(g Guest) Guest {
+	c := Guest{
+		ExternalID:     g.ExternalID,
+		EventID:        r.Event.ID,
+		FirstName:      g.FirstName,
+		LastName:       g.LastName,
+		EntourageCount: g.EntourageCount,
+		Comment:        g.Comment,
+		Email:          g.Email,
+		CategoryID:     r.Categories[g.CategoryID],
+		ParentGuestID:  r.Guests[g.ParentGuestID],
+	}
+	if len(g.CustomFields) > 0 {
+		c.CustomFields = CustomFields{}
+		for k, v := range g.CustomFields {
+			if id, ok := r.CustomFields[k]; ok {
+				k = id
+			}
+			c.CustomFields[k] = v
+		}
+	}
+	return c
+}
+
+func (r *CloneReport) writePlan(w io.Writer, source Event, categories Categories, guests Guests) error {
+	_, err := fmt.Fprintf(w, "clone event %v (%v) as %q, %v - %v, state %v\n",
+		source.Name, source.ID, r.Event.Name, r.Event.StartDate.Format(time.RFC3339), r.Event.EndDate.Format(time.RFC3339), r.Event.State)
+	if err != nil {
+		return err
+	}
+	for _, d := range source.CustomFieldDefinitions {
+		fmt.Fprintf(w, "  custom field %v (%v, %v)\n", d.Name, d.Type, d.ID)
+	}
+	for _, c := range categories {
+		fmt.Fprintf(w, "  category %v (%v)\n", c.Name, c.ID)
+	}
+	for _, g := range guests {
+		if contains(r.Orphans, g.ID) {
+			fmt.Fprintf(w, "  guest %v %v (%v), without its parent %v\n", g.FirstName, g.LastName, g.ID, g.ParentGuestID)
+			continue
+		}
+		fmt.Fprintf(w, "  guest %v %v (%v)\n", g.FirstName, g.LastName, g.ID)
+	}
+	_, err = fmt.Fprintf(w, "%d custom fields, %d categories, %d guests\n", len(source.CustomFieldDefinitions), len(categories), len(guests))
+	return err
+}
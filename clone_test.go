@@ -0,0 +1,143 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func cloneSeed() sweaptest.Seed {
+	start := time.Date(2023, 5, 10, 9, 0, 0, 0, time.UTC)
+	return sweaptest.Seed{
+		Events: sweap.Events{{
+			ID: "conf-2023", Name: "Conference 2023", State: string(sweap.CLOSED), ZoneId: "Europe/Berlin",
+			StartDate: start, EndDate: start.Add(8 * time.Hour),
+			CustomFieldDefinitions: []sweap.CustomFieldDefinitions{{ID: "field-company", Name: "company", Type: "TEXT"}},
+		}},
+		Categories: sweap.Categories{
+			{ID: "cat-speaker", EventID: "conf-2023", Name: "Speaker", ColorHex: "#ff0000", SortIndex: 0},
+			{ID: "cat-visitor", EventID: "conf-2023", Name: "Visitor", SortIndex: 1},
+		},
+		Guests: sweap.Guests{
+			{ID: "g-anna", EventID: "conf-2023", FirstName: "Anna", CategoryID: "cat-speaker", InvitationState: sweap.ACCEPTED,
+				CustomFields: sweap.CustomFields{"field-company": "ACME"}},
+			{ID: "g-plus-one", EventID: "conf-2023", FirstName: "Ben", ParentGuestID: "g-anna", CategoryID: "cat-speaker", InvitationState: sweap.ACCEPTED},
+			{ID: "g-carl", EventID: "conf-2023", FirstName: "Carl", CategoryID: "cat-visitor", InvitationState: sweap.DECLINED},
+		},
+	}
+}
+
+func TestCloneEvent(t *testing.T) {
+	srv := sweaptest.NewServer(cloneSeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	report, err := api.CloneEvent("conf-2023", sweap.CloneOptions{
+		Name:             "Conference 2024",
+		Shift:            366 * 24 * time.Hour,
+		Guests:           true,
+		InvitationStates: []sweap.InvitationState{sweap.ACCEPTED},
+	})
+	assert.Nil(t, err)
+
+	e, ok := srv.Event(report.Event.ID)
+	assert.True(t, ok)
+	assert.Equal(t, "Conference 2024", e.Name)
+	assert.Equal(t, string(sweap.DRAFT), e.State)
+	assert.Equal(t, time.Date(2024, 5, 10, 9, 0, 0, 0, time.UTC), e.StartDate.UTC())
+	assert.Equal(t, "company", e.CustomFieldDefinitions[0].Name)
+	newField := report.CustomFields["field-company"]
+	assert.NotEmpty(t, newField)
+	assert.NotEqual(t, "field-company", newField)
+
+	categories := srv.Categories(e.ID)
+	assert.Len(t, categories, 2)
+	assert.Equal(t, report.Categories["cat-speaker"], categories[0].ID)
+	assert.Equal(t, "#ff0000", categories[0].ColorHex)
+
+	guests := srv.Guests(e.ID)
+	assert.Len(t, guests, 2)
+	assert.Len(t, report.Guests, 2)
+	anna, _ := srv.Guest(report.Guests["g-anna"])
+	assert.Equal(t, report.Categories["cat-speaker"], anna.CategoryID)
+	assert.Equal(t, "ACME", anna.CustomFields[newField])
+	assert.Equal(t, sweap.NONE, anna.InvitationState)
+	ben, _ := srv.Guest(report.Guests["g-plus-one"])
+	assert.Equal(t, anna.ID, ben.ParentGuestID)
+
+	assert.Len(t, srv.Guests("conf-2023"), 3)
+}
+
+func TestCloneEventByCategory(t *testing.T) {
+	seed := cloneSeed()
+	seed.Guests = append(seed.Guests, sweap.Guest{ID: "g-dora", EventID: "conf-2023", FirstName: "Dora", ParentGuestID: "g-anna", CategoryID: "cat-visitor"})
+	srv := sweaptest.NewServer(seed)
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	report, err := api.CloneEvent("conf-2023", sweap.CloneOptions{Guests: true, CategoryIDs: []string{"cat-visitor"}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Conference 2023", report.Event.Name)
+	guests := srv.Guests(report.Event.ID)
+	assert.Len(t, guests, 2)
+	assert.Equal(t, "Carl", guests[0].FirstName)
+	assert.Equal(t, report.Categories["cat-visitor"], guests[0].CategoryID)
+
+	// the parent of Dora is a speaker and not copied
+	assert.Equal(t, []string{"g-dora"}, report.Orphans)
+	dora, _ := srv.Guest(report.Guests["g-dora"])
+	assert.Empty(t, dora.ParentGuestID)
+
+	report, err = api.CloneEvent("conf-2023", sweap.CloneOptions{})
+	assert.Nil(t, err)
+	assert.Empty(t, report.Guests)
+	assert.Empty(t, srv.Guests(report.Event.ID))
+	assert.Len(t, srv.Categories(report.Event.ID), 2)
+}
+
+func TestCloneEventDryRun(t *testing.T) {
+	srv := sweaptest.NewServer(cloneSeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	var out bytes.Buffer
+	report, err := api.CloneEvent("conf-2023", sweap.CloneOptions{Name: "Conference 2024", Guests: true, DryRun: true, Out: &out})
+	assert.Nil(t, err)
+	assert.True(t, report.DryRun)
+	assert.Empty(t, report.Event.ID)
+	assert.Contains(t, report.Guests, "g-carl")
+	assert.Contains(t, out.String(), `as "Conference 2024"`)
+	assert.Contains(t, out.String(), "category Speaker (cat-speaker)")
+	assert.Contains(t, out.String(), "1 custom fields, 2 categories, 3 guests")
+	assert.Empty(t, report.Orphans)
+
+	for _, r := range srv.Requests() {
+		assert.Equal(t, "GET", r.Method)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// OptionOptimisticLocking makes UpdateGuest and UpdateEvent fail with a *ConflictError if the
+// Version of the given guest or event differs from the one on the server, instead of overwriting
+// changes made in the meantime. The version is checked with an additional GET before the update.
+func OptionOptimisticLocking() func(*Client) {
+	return func(c *Client) {
+		c.checkVersion = true
+	}
+}
+
+// conflictRetries is how often UpdateGuestWith repeats an update after a conflict.
+var conflictRetries = RetryPolicy{MaxAttempts: 5, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.5}
+
+// UpdateGuestWith reads the guest, lets mutate change it and writes it back. If the guest has been
+// changed by someone else in between, the read, mutate and write is repeated. An error returned
+// by mutate aborts the update. The returned error matches ErrConflict if all attempts failed.
+func (api *Client) UpdateGuestWith(ctx context.Context, guestId string, mutate func(*Guest) error) (*Guest, error) {
+	return api.modifyGuest(ctx, guestId, func(g *Guest) (bool, error) {
+		return true, mutate(g)
+	}, true)
+}
+
+// modifyGuest reads, mutates and writes a guest, repeating on conflicts. The guest is
+// not written if mutate reports no change. With recheck the version is compared with the
+// server again before the write, for mutations that take a while; otherwise the guest just
+// read is taken as current. explicit is passed on to updateGuest.
+func (api *Client) modifyGuest(ctx context.Context, guestId string, mutate func(*Guest) (bool, error), recheck bool, explicit ...string) (*Guest, error) {
+	if guestId == "" {
+		return nil, SweapLibraryError{"no guest ID given"}
+	}
+
+	for attempt := 1; ; attempt++ {
+		g, err := api.GetGuestByIdContext(ctx, guestId)
+		if err != nil {
+			return nil, err
+		}
+		version := g.Version
+		changed, err := mutate(g)
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			return g, nil
+		}
+		g.ID, g.Version = guestId, version
+
+		updated, err := api.updateGuest(ctx, *g, recheck, explicit...)
+		if err == nil || !errors.Is(err, ErrConflict) || attempt >= conflictRetries.MaxAttempts {
+			return updated, err
+		}
+		api.Debugf("conflict updating guest %v, retrying (attempt %d/%d)", guestId, attempt, conflictRetries.MaxAttempts)
+		if err := sleepContext(ctx, conflictRetries.backoff(attempt)); err != nil {
+			return nil, err
+		}
+	}
+}
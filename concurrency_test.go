@@ -0,0 +1,127 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func concurrencySeed() sweaptest.Seed {
+	return sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Versions"}},
+		Guests: sweap.Guests{{ID: "g-1", EventID: "event-1", FirstName: "Anna", EntourageCount: 1}},
+	}
+}
+
+func TestOptimisticLocking(t *testing.T) {
+	srv := sweaptest.NewServer(concurrencySeed())
+	defer srv.Close()
+	api, _ := srv.Client(sweap.OptionOptimisticLocking())
+
+	g, err := api.GetGuestById("g-1")
+	assert.Nil(t, err)
+	srv.UpdateGuest("g-1", func(g *sweap.Guest) { g.LastName = "Adams" })
+
+	g.FirstName = "Annabel"
+	_, err = api.UpdateGuest(*g)
+	assert.True(t, errors.Is(err, sweap.ErrConflict))
+	var ce *sweap.ConflictError
+	assert.True(t, errors.As(err, &ce))
+	assert.Equal(t, g.Version+1, ce.CurrentVersion)
+	stored, _ := srv.Guest("g-1")
+	assert.Equal(t, "Anna", stored.FirstName)
+
+	g, _ = api.GetGuestById("g-1")
+	g.FirstName = "Annabel"
+	_, err = api.UpdateGuest(*g)
+	assert.Nil(t, err)
+
+	e, _ := api.GetEventById("event-1")
+	e.Version--
+	_, err = api.UpdateEvent(*e)
+	assert.True(t, errors.Is(err, sweap.ErrConflict))
+	e.Version++
+	_, err = api.UpdateEvent(*e)
+	assert.Nil(t, err)
+}
+
+func TestUpdateWithoutLocking(t *testing.T) {
+	srv := sweaptest.NewServer(concurrencySeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	g, _ := api.GetGuestById("g-1")
+	srv.UpdateGuest("g-1", func(g *sweap.Guest) { g.LastName = "Adams" })
+	g.FirstName = "Annabel"
+	_, err := api.UpdateGuest(*g)
+	assert.Nil(t, err)
+	stored, _ := srv.Guest("g-1")
+	assert.Equal(t, "", stored.LastName) // the concurrent change is lost
+	// the version is not checked, there is no read before the update
+	var methods []string
+	for _, r := range srv.Requests() {
+		methods = append(methods, r.Method)
+	}
+	assert.Equal(t, []string{"GET", "PUT"}, methods)
+}
+
+func TestUpdateGuestWith(t *testing.T) {
+	srv := sweaptest.NewServer(concurrencySeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	calls := 0
+	updated, err := api.UpdateGuestWith(context.Background(), "g-1", func(g *sweap.Guest) error {
+		calls++
+		if calls == 1 {
+			// someone else changes the guest between our read and write
+			srv.UpdateGuest("g-1", func(g *sweap.Guest) { g.EntourageCount++ })
+		}
+		g.EntourageCount++
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, 3, updated.EntourageCount)
+	stored, _ := srv.Guest("g-1")
+	assert.Equal(t, 3, stored.EntourageCount)
+
+	_, err = api.UpdateGuestWith(context.Background(), "g-1", func(g *sweap.Guest) error {
+		srv.UpdateGuest("g-1", func(g *sweap.Guest) {})
+		return nil
+	})
+	assert.True(t, errors.Is(err, sweap.ErrConflict))
+
+	abort := errors.New("abort")
+	_, err = api.UpdateGuestWith(context.Background(), "g-1", func(g *sweap.Guest) error { return abort })
+	assert.Equal(t, abort, err)
+
+	_, err = api.UpdateGuestWith(context.Background(), "missing", func(g *sweap.Guest) error { return nil })
+	assert.True(t, errors.Is(err, sweap.ErrNotFound))
+}
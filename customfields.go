@@ -0,0 +1,318 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CustomFieldType is the Type of a custom field definition.
+type CustomFieldType string
+
+const (
+	TEXTFIELD     CustomFieldType = "TEXT"
+	NUMBERFIELD   CustomFieldType = "NUMBER"
+	DATEFIELD     CustomFieldType = "DATE"
+	SELECTFIELD   CustomFieldType = "SELECT"
+	CHECKBOXFIELD CustomFieldType = "CHECKBOX"
+)
+
+// CustomFieldDateLayout is the format of DATE custom field values.
+const CustomFieldDateLayout = "2006-01-02"
+
+// ErrCustomFieldNotSet is returned by the Guest getters if the guest has no value for a custom field.
+var ErrCustomFieldNotSet = errors.New("sweap: custom field not set")
+
+// CustomField is the typed form of a CustomFieldDefinitions, see CustomFieldDefinitions.Typed.
+type CustomField interface {
+	Definition() CustomFieldDefinitions
+	// Validate checks a value as stored in Guest.CustomFields. The empty value is always valid.
+	Validate(value string) error
+}
+
+type TextField struct{ CustomFieldDefinitions }
+type NumberField struct{ CustomFieldDefinitions }
+type DateField struct{ CustomFieldDefinitions }
+type CheckboxField struct{ CustomFieldDefinitions }
+
+// SelectField only accepts one of its Choices.
+type SelectField struct {
+	CustomFieldDefinitions
+	Choices []string
+}
+
+func (f TextField) Definition() CustomFieldDefinitions     { return f.CustomFieldDefinitions }
+func (f NumberField) Definition() CustomFieldDefinitions   { return f.CustomFieldDefinitions }
+func (f DateField) Definition() CustomFieldDefinitions     { return f.CustomFieldDefinitions }
+func (f CheckboxField) Definition() CustomFieldDefinitions { return f.CustomFieldDefinitions }
+func (f SelectField) Definition() CustomFieldDefinitions   { return f.CustomFieldDefinitions }
+
+func (f TextField) Validate(value string) error { return nil }
+
+func (f NumberField) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		return fmt.Errorf("%q is not a number", value)
+	}
+	return nil
+}
+
+func (f DateField) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := time.Parse(CustomFieldDateLayout, value); err != nil {
+		return fmt.Errorf("%q is not a date like %v", value, CustomFieldDateLayout)
+	}
+	return nil
+}
+
+func (f CheckboxField) Validate(value string) error {
+	if value == "" {
+		return nil
+	}
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("%q is not true or false", value)
+	}
+	return nil
+}
+
+func (f SelectField) Validate(value string) error {
+	if value == "" || contains(f.Choices, value) {
+		return nil
+	}
+	return fmt.Errorf("%q is not one of %v", value, strings.Join(f.Choices, ", "))
+}
+
+// Typed returns the typed form of the definition. Unknown types are treated as text.
+func (d CustomFieldDefinitions) Typed() CustomField {
+	switch CustomFieldType(strings.ToUpper(d.Type)) {
+	case NUMBERFIELD:
+		return NumberField{d}
+	case DATEFIELD:
+		return DateField{d}
+	case CHECKBOXFIELD:
+		return CheckboxField{d}
+	case SELECTFIELD:
+		return SelectField{d, d.Choices()}
+	}
+	return TextField{d}
+}
+
+// Choices decodes the Options of a select field. Options are either plain strings
+// or objects, of which the value, name, label or id is used.
+func (d CustomFieldDefinitions) Choices() []string {
+	var choices []string
+	switch opts := d.Options.(type) {
+	case []string:
+		return append(choices, opts...)
+	case []interface{}:
+		for _, o := range opts {
+			switch o := o.(type) {
+			case string:
+				choices = append(choices, o)
+			case map[string]interface{}:
+				for _, k := range []string{"value", "name", "label", "id"} {
+					if s, ok := o[k].(string); ok {
+						choices = append(choices, s)
+						break
+					}
+				}
+			}
+		}
+	}
+	return choices
+}
+
+// ValidateCustomFields checks custom field values against the definitions of their event.
+// Values for unknown fields and values not matching the type of their field are reported
+// in a *ValidationError, which matches ErrValidation.
+func ValidateCustomFields(defs []CustomFieldDefinitions, fields CustomFields) error {
+	typed := make(map[string]CustomField, len(defs))
+	for _, d := range defs {
+		typed[d.ID] = d.Typed()
+	}
+
+	var errs []FieldError
+	for id, v := range fields {
+		f, ok := typed[id]
+		if !ok {
+			errs = append(errs, FieldError{Field: "customFields." + id, Message: "unknown custom field"})
+			continue
+		}
+		if err := f.Validate(v); err != nil {
+			errs = append(errs, FieldError{Field: "customFields." + id, Message: err.Error()})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return &ValidationError{
+		SweapError: &SweapError{Status: VALIDATION_EXCEPTION, Message: "custom fields do not match the definitions of the event"},
+		Fields:     errs,
+	}
+}
+
+// CustomField returns the raw value of a custom field or ErrCustomFieldNotSet.
+func (g Guest) CustomField(id string) (string, error) {
+	v, ok := g.CustomFields[id]
+	if !ok || v == "" {
+		return "", ErrCustomFieldNotSet
+	}
+	return v, nil
+}
+
+// CustomFieldNumber returns the value of a NUMBER custom field.
+func (g Guest) CustomFieldNumber(id string) (float64, error) {
+	v, err := g.CustomField(id)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+// CustomFieldDate returns the value of a DATE custom field.
+func (g Guest) CustomFieldDate(id string) (time.Time, error) {
+	v, err := g.CustomField(id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(CustomFieldDateLayout, v)
+}
+
+// CustomFieldBool returns the value of a CHECKBOX custom field.
+func (g Guest) CustomFieldBool(id string) (bool, error) {
+	v, err := g.CustomField(id)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(v)
+}
+
+// SetCustomField sets the value of a custom field, converting strings, numbers, booleans
+// and time.Time (as date) into their stored form. A nil value removes the field.
+func (g *Guest) SetCustomField(id string, value interface{}) error {
+	var v string
+	switch value := value.(type) {
+	case nil:
+		delete(g.CustomFields, id)
+		return nil
+	case string:
+		v = value
+	case bool:
+		v = strconv.FormatBool(value)
+	case int:
+		v = strconv.Itoa(value)
+	case int64:
+		v = strconv.FormatInt(value, 10)
+	case float64:
+		v = strconv.FormatFloat(value, 'f', -1, 64)
+	case time.Time:
+		v = value.Format(CustomFieldDateLayout)
+	default:
+		return SweapLibraryError{fmt.Sprintf("unsupported custom field value of type %T", value)}
+	}
+
+	if g.CustomFields == nil {
+		g.CustomFields = CustomFields{}
+	}
+	g.CustomFields[id] = v
+	return nil
+}
+
+// OptionValidateCustomFields validates the custom fields of guests against the definitions
+// of their event before CreateGuest and UpdateGuest. The definitions are retrieved with
+// GetEventById and cached for ttl, or one minute if ttl is 0.
+func OptionValidateCustomFields(ttl time.Duration) func(*Client) {
+	return func(c *Client) {
+		if ttl <= 0 {
+			ttl = time.Minute
+		}
+		c.fieldDefs = &definitionCache{ttl: ttl, entries: map[string]cachedDefinitions{}}
+	}
+}
+
+type cachedDefinitions struct {
+	defs    []CustomFieldDefinitions
+	fetched time.Time
+}
+
+// definitionCache holds the custom field definitions of events.
+type definitionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedDefinitions
+}
+
+func (c *definitionCache) get(eventID string) ([]CustomFieldDefinitions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[eventID]
+	if !ok || time.Since(e.fetched) > c.ttl {
+		return nil, false
+	}
+	return e.defs, true
+}
+
+func (c *definitionCache) put(eventID string, defs []CustomFieldDefinitions) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[eventID] = cachedDefinitions{defs: defs, fetched: time.Now()}
+}
+
+func (c *definitionCache) forget(eventID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, eventID)
+}
+
+// validateGuestFields validates the custom fields of g if OptionValidateCustomFields is set.
+func (api *Client) validateGuestFields(ctx context.Context, g Guest) error {
+	if api.fieldDefs == nil || len(g.CustomFields) == 0 {
+		return nil
+	}
+
+	defs, ok := api.fieldDefs.get(g.EventID)
+	if !ok {
+		e, err := api.GetEventByIdContext(ctx, g.EventID)
+		if err != nil {
+			return err
+		}
+		defs = e.CustomFieldDefinitions
+		api.fieldDefs.put(g.EventID, defs)
+	}
+	return ValidateCustomFields(defs, g.CustomFields)
+}
@@ -0,0 +1,150 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+const definitionsJSON = `[
+	{"id": "title", "name": "Title", "type": "TEXT", "sortIndex": 1},
+	{"id": "seats", "name": "Seats", "type": "NUMBER", "sortIndex": 2},
+	{"id": "arrival", "name": "Arrival", "type": "DATE", "sortIndex": 3},
+	{"id": "diet", "name": "Diet", "type": "SELECT", "sortIndex": 4, "options": ["none", "vegetarian", {"value": "vegan", "label": "Vegan"}]},
+	{"id": "parking", "name": "Parking", "type": "CHECKBOX", "sortIndex": 5}
+]`
+
+func definitions(t *testing.T) []sweap.CustomFieldDefinitions {
+	var defs []sweap.CustomFieldDefinitions
+	assert.Nil(t, json.Unmarshal([]byte(definitionsJSON), &defs))
+	return defs
+}
+
+func TestCustomFieldTypes(t *testing.T) {
+	defs := definitions(t)
+
+	assert.IsType(t, sweap.TextField{}, defs[0].Typed())
+	assert.IsType(t, sweap.NumberField{}, defs[1].Typed())
+	assert.IsType(t, sweap.DateField{}, defs[2].Typed())
+	assert.IsType(t, sweap.CheckboxField{}, defs[4].Typed())
+	sel, ok := defs[3].Typed().(sweap.SelectField)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"none", "vegetarian", "vegan"}, sel.Choices)
+	assert.Equal(t, "Diet", sel.Definition().Name)
+
+	unknown := sweap.CustomFieldDefinitions{ID: "x", Type: "RICH_TEXT"}
+	assert.IsType(t, sweap.TextField{}, unknown.Typed())
+}
+
+func TestGuestCustomFields(t *testing.T) {
+	g := sweap.Guest{}
+	arrival := time.Date(2023, 9, 1, 15, 30, 0, 0, time.UTC)
+
+	assert.Nil(t, g.SetCustomField("title", "Dr."))
+	assert.Nil(t, g.SetCustomField("seats", 2))
+	assert.Nil(t, g.SetCustomField("arrival", arrival))
+	assert.Nil(t, g.SetCustomField("parking", true))
+	assert.Nil(t, g.SetCustomField("ratio", 0.5))
+	assert.NotNil(t, g.SetCustomField("diet", []string{"vegan"}))
+	assert.Equal(t, sweap.CustomFields{"title": "Dr.", "seats": "2", "arrival": "2023-09-01", "parking": "true", "ratio": "0.5"}, g.CustomFields)
+
+	title, err := g.CustomField("title")
+	assert.Nil(t, err)
+	assert.Equal(t, "Dr.", title)
+	seats, err := g.CustomFieldNumber("seats")
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, seats)
+	date, err := g.CustomFieldDate("arrival")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC), date)
+	parking, err := g.CustomFieldBool("parking")
+	assert.Nil(t, err)
+	assert.True(t, parking)
+
+	_, err = g.CustomFieldNumber("diet")
+	assert.True(t, errors.Is(err, sweap.ErrCustomFieldNotSet))
+	_, err = g.CustomFieldNumber("title")
+	assert.NotNil(t, err)
+
+	assert.Nil(t, g.SetCustomField("title", nil))
+	assert.NotContains(t, g.CustomFields, "title")
+}
+
+func TestValidateCustomFields(t *testing.T) {
+	defs := definitions(t)
+
+	assert.Nil(t, sweap.ValidateCustomFields(defs, sweap.CustomFields{
+		"title": "anything", "seats": "2.5", "arrival": "2023-09-01", "diet": "vegan", "parking": "false",
+	}))
+	assert.Nil(t, sweap.ValidateCustomFields(defs, sweap.CustomFields{"seats": "", "diet": ""}))
+
+	err := sweap.ValidateCustomFields(defs, sweap.CustomFields{
+		"seats": "two", "arrival": "01.09.2023", "diet": "paleo", "parking": "yes", "shoe": "42",
+	})
+	assert.True(t, errors.Is(err, sweap.ErrValidation))
+	var ve *sweap.ValidationError
+	assert.True(t, errors.As(err, &ve))
+	fields := []string{}
+	for _, f := range ve.Fields {
+		fields = append(fields, f.Field)
+	}
+	assert.Equal(t, []string{"customFields.arrival", "customFields.diet", "customFields.parking", "customFields.seats", "customFields.shoe"}, fields)
+}
+
+func TestValidateCustomFieldsBeforeWrite(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Fields", CustomFieldDefinitions: definitions(t)}},
+	})
+	defer srv.Close()
+	api, _ := srv.Client(sweap.OptionValidateCustomFields(0))
+
+	g := sweap.Guest{EventID: "event-1", FirstName: "Anna"}
+	assert.Nil(t, g.SetCustomField("seats", "many"))
+	_, err := api.CreateGuest(g)
+	assert.True(t, errors.Is(err, sweap.ErrValidation))
+	assert.Empty(t, srv.Guests("event-1"))
+
+	assert.Nil(t, g.SetCustomField("seats", 3))
+	created, err := api.CreateGuest(g)
+	assert.Nil(t, err)
+
+	assert.Nil(t, created.SetCustomField("diet", "paleo"))
+	_, err = api.UpdateGuest(*created)
+	assert.True(t, errors.Is(err, sweap.ErrValidation))
+
+	// the definitions are only retrieved once
+	gets := 0
+	for _, r := range srv.Requests() {
+		if r.Method == "GET" {
+			gets++
+		}
+	}
+	assert.Equal(t, 1, gets)
+}
@@ -0,0 +1,388 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Headers of webhook requests.
+const (
+	SignatureHeader = "X-Sweap-Signature"
+	TimestampHeader = "X-Sweap-Timestamp"
+	DeliveryHeader  = "X-Sweap-Delivery"
+)
+
+// WebhookPayload is the JSON body POSTed for each GuestUpdate.
+type WebhookPayload struct {
+	ID        string    `json:"id"`
+	EventType string    `json:"eventType"`
+	Timestamp time.Time `json:"timestamp"`
+	Guest     Guest     `json:"guest"`
+}
+
+// WebhookEndpoint is a receiver of webhook requests. Requests are signed with Secret.
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// DeliveryMetrics counts the deliveries to an endpoint.
+type DeliveryMetrics struct {
+	Delivered    int64  // successful deliveries
+	Failed       int64  // failed attempts, including those retried later
+	Retried      int64  // attempts after a failure
+	DeadLettered int64  // deliveries given up
+	Skipped      int64  // updates that could not be dispatched, e.g. because their value is not a Guest
+	LastError    string // error of the last failed attempt
+}
+
+// SignPayload returns the signature of a webhook request: the hex encoded HMAC-SHA256
+// of the timestamp, a dot and the body, prefixed with "sha256=".
+func SignPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature reports whether signature is the valid signature of timestamp and body.
+func VerifySignature(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignPayload(secret, timestamp, body)), []byte(signature))
+}
+
+type DispatcherOptions func(*Dispatcher)
+
+// DispatcherHTTPClient sets the http.Client used for deliveries. Defaults to a client with a 30s timeout.
+func DispatcherHTTPClient(hc *http.Client) func(*Dispatcher) {
+	return func(d *Dispatcher) {
+		d.client = hc
+	}
+}
+
+// DispatcherRetry sets how failed deliveries are retried. Defaults to NewRetryPolicy().
+func DispatcherRetry(p RetryPolicy) func(*Dispatcher) {
+	return func(d *Dispatcher) {
+		d.retry = p
+	}
+}
+
+// DispatcherDeadLetterDir sets the directory receiving deliveries that could not be delivered.
+// Each endpoint gets a JSON Lines file. Without a directory they are only counted.
+func DispatcherDeadLetterDir(dir string) func(*Dispatcher) {
+	return func(d *Dispatcher) {
+		d.deadLetterDir = dir
+	}
+}
+
+// DispatcherQueueSize sets how many deliveries may wait for each endpoint before Run blocks. Defaults to 256.
+func DispatcherQueueSize(n int) func(*Dispatcher) {
+	return func(d *Dispatcher) {
+		d.queueSize = n
+	}
+}
+
+// Dispatcher fans out guest updates, e.g. from Listen, to webhook endpoints.
+// Every endpoint has its own queue, so that a slow or failing endpoint does not delay the others.
+type Dispatcher struct {
+	endpoints     []WebhookEndpoint
+	client        *http.Client
+	retry         RetryPolicy
+	deadLetterDir string
+	queueSize     int
+
+	mu      sync.Mutex
+	metrics map[string]*DeliveryMetrics
+}
+
+// NewDispatcher creates a Dispatcher for the given endpoints.
+func NewDispatcher(endpoints []WebhookEndpoint, options ...DispatcherOptions) *Dispatcher {
+	d := &Dispatcher{
+		endpoints: endpoints,
+		client:    &http.Client{Timeout: 30 * time.Second},
+		retry:     NewRetryPolicy(),
+		queueSize: messageBufferSize,
+		metrics:   make(map[string]*DeliveryMetrics),
+	}
+	for _, opt := range options {
+		opt(d)
+	}
+	for _, e := range endpoints {
+		d.metrics[e.URL] = &DeliveryMetrics{}
+	}
+	return d
+}
+
+// Metrics returns a snapshot of the delivery metrics by endpoint URL.
+func (d *Dispatcher) Metrics() map[string]DeliveryMetrics {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m := make(map[string]DeliveryMetrics, len(d.metrics))
+	for url, dm := range d.metrics {
+		m[url] = *dm
+	}
+	return m
+}
+
+func (d *Dispatcher) count(url string, f func(*DeliveryMetrics)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f(d.metrics[url])
+}
+
+// delivery is a payload on its way to an endpoint.
+type delivery struct {
+	id       string
+	body     []byte
+	attempts int
+	due      time.Time
+}
+
+// Run delivers the updates until the channel is closed and all deliveries are done, or ctx is done.
+// Deliveries still queued or waiting for a retry when ctx is done are dead-lettered. Updates that
+// cannot be dispatched, e.g. because their value is not a Guest, are skipped and counted in the
+// metrics of every endpoint.
+func (d *Dispatcher) Run(ctx context.Context, updates <-chan *GuestUpdate) error {
+	queues := make([]chan *delivery, len(d.endpoints))
+	var wg sync.WaitGroup
+	for i, e := range d.endpoints {
+		queues[i] = make(chan *delivery, d.queueSize)
+		wg.Add(1)
+		go func(e WebhookEndpoint, queue chan *delivery) {
+			defer wg.Done()
+			d.work(ctx, e, queue)
+		}(e, queues[i])
+	}
+
+	err := d.fanOut(ctx, updates, queues)
+	for _, q := range queues {
+		close(q)
+	}
+	wg.Wait()
+	return err
+}
+
+func (d *Dispatcher) fanOut(ctx context.Context, updates <-chan *GuestUpdate, queues []chan *delivery) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case u, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			body, id, err := newWebhookPayload(u)
+			if err != nil {
+				for _, e := range d.endpoints {
+					d.count(e.URL, func(m *DeliveryMetrics) {
+						m.Skipped++
+						m.LastError = err.Error()
+					})
+				}
+				continue
+			}
+			for _, q := range queues {
+				select {
+				case q <- &delivery{id: id, body: body}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+func newWebhookPayload(u *GuestUpdate) ([]byte, string, error) {
+	var guest Guest
+	switch v := u.Value.(type) {
+	case Guest:
+		guest = v
+	case *Guest:
+		guest = *v
+	default:
+		return nil, "", SweapLibraryError{Message: fmt.Sprintf("cannot dispatch update of type %T", u.Value)}
+	}
+
+	id := newDeliveryID()
+	body, err := json.Marshal(WebhookPayload{ID: id, EventType: u.EventType, Timestamp: time.Now().UTC(), Guest: guest})
+	return body, id, err
+}
+
+// work delivers the deliveries of one endpoint. New deliveries are sent right away,
+// failed ones wait in a retry queue ordered by their due time.
+func (d *Dispatcher) work(ctx context.Context, e WebhookEndpoint, queue <-chan *delivery) {
+	var retries []*delivery
+
+	for queue != nil || len(retries) > 0 {
+		var timer *time.Timer
+		var due <-chan time.Time
+		if len(retries) > 0 {
+			timer = time.NewTimer(time.Until(retries[0].due))
+			due = timer.C
+		}
+
+		var dl *delivery
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			for _, r := range retries {
+				d.deadLetter(e, r)
+			}
+			if queue != nil {
+				// Run closes the queue once the fan out has stopped
+				for next := range queue {
+					d.deadLetter(e, next)
+				}
+			}
+			return
+		case next, ok := <-queue:
+			stopTimer(timer)
+			if !ok {
+				queue = nil
+				continue
+			}
+			dl = next
+		case <-due:
+			dl, retries = retries[0], retries[1:]
+			d.count(e.URL, func(m *DeliveryMetrics) { m.Retried++ })
+		}
+
+		if err := d.post(ctx, e, dl); err != nil {
+			dl.attempts++
+			d.count(e.URL, func(m *DeliveryMetrics) {
+				m.Failed++
+				m.LastError = err.Error()
+			})
+			if dl.attempts >= d.retry.MaxAttempts {
+				d.deadLetter(e, dl)
+				continue
+			}
+			dl.due = time.Now().Add(d.retry.backoff(dl.attempts))
+			retries = append(retries, dl)
+			sort.SliceStable(retries, func(i, j int) bool { return retries[i].due.Before(retries[j].due) })
+			continue
+		}
+		d.count(e.URL, func(m *DeliveryMetrics) { m.Delivered++ })
+	}
+}
+
+func (d *Dispatcher) post(ctx context.Context, e WebhookEndpoint, dl *delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(DeliveryHeader, dl.id)
+	req.Header.Set(SignatureHeader, SignPayload(e.Secret, ts, dl.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return StatusCodeError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// deadLetterEntry is a line of a dead letter file.
+type deadLetterEntry struct {
+	Endpoint  string          `json:"endpoint"`
+	Attempts  int             `json:"attempts"`
+	FailedAt  time.Time       `json:"failedAt"`
+	LastError string          `json:"lastError,omitempty"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+func (d *Dispatcher) deadLetter(e WebhookEndpoint, dl *delivery) {
+	var lastError string
+	d.count(e.URL, func(m *DeliveryMetrics) {
+		m.DeadLettered++
+		lastError = m.LastError
+	})
+	if d.deadLetterDir == "" {
+		return
+	}
+
+	line, err := json.Marshal(deadLetterEntry{Endpoint: e.URL, Attempts: dl.attempts, FailedAt: time.Now().UTC(), LastError: lastError, Payload: dl.body})
+	if err == nil {
+		err = appendLine(DeadLetterFile(d.deadLetterDir, e.URL), line)
+	}
+	if err != nil {
+		d.count(e.URL, func(m *DeliveryMetrics) { m.LastError = "dead-lettering failed: " + err.Error() })
+	}
+}
+
+// DeadLetterFile returns the file in dir receiving the dead letters of the endpoint with the given URL.
+func DeadLetterFile(dir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, "deadletter-"+hex.EncodeToString(sum[:8])+".jsonl")
+}
+
+func appendLine(path string, line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func stopTimer(t *time.Timer) {
+	if t != nil {
+		t.Stop()
+	}
+}
+
+func newDeliveryID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
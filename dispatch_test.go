@@ -0,0 +1,202 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	payloads []sweap.WebhookPayload
+}
+
+func (rc *receiver) handler(t *testing.T, secret string) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, err := strconv.ParseInt(r.Header.Get(sweap.TimestampHeader), 10, 64)
+		assert.Nil(t, err)
+		assert.True(t, sweap.VerifySignature(secret, ts, body, r.Header.Get(sweap.SignatureHeader)))
+
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		if rc.failures != 0 {
+			if rc.failures > 0 {
+				rc.failures--
+			}
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var p sweap.WebhookPayload
+		assert.Nil(t, json.Unmarshal(body, &p))
+		assert.Equal(t, p.ID, r.Header.Get(sweap.DeliveryHeader))
+		rc.payloads = append(rc.payloads, p)
+	})
+}
+
+func TestSignature(t *testing.T) {
+	sig := sweap.SignPayload("secret", 1700000000, []byte(`{}`))
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", sig)
+	assert.True(t, sweap.VerifySignature("secret", 1700000000, []byte(`{}`), sig))
+	assert.False(t, sweap.VerifySignature("other", 1700000000, []byte(`{}`), sig))
+	assert.False(t, sweap.VerifySignature("secret", 1700000001, []byte(`{}`), sig))
+	assert.False(t, sweap.VerifySignature("secret", 1700000000, []byte(`{ }`), sig))
+}
+
+func TestDispatcher(t *testing.T) {
+	healthy, flaky, broken := &receiver{}, &receiver{failures: 2}, &receiver{failures: -1}
+	healthySrv := httptest.NewServer(healthy.handler(t, "s1"))
+	defer healthySrv.Close()
+	flakySrv := httptest.NewServer(flaky.handler(t, "s2"))
+	defer flakySrv.Close()
+	brokenSrv := httptest.NewServer(broken.handler(t, "s3"))
+	defer brokenSrv.Close()
+
+	dir := t.TempDir()
+	d := sweap.NewDispatcher([]sweap.WebhookEndpoint{
+		{URL: healthySrv.URL, Secret: "s1"},
+		{URL: flakySrv.URL, Secret: "s2"},
+		{URL: brokenSrv.URL, Secret: "s3"},
+	},
+		sweap.DispatcherRetry(sweap.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}),
+		sweap.DispatcherDeadLetterDir(dir))
+
+	updates := make(chan *sweap.GuestUpdate, 3)
+	updates <- &sweap.GuestUpdate{EventType: sweap.NEWGUEST, Value: sweap.Guest{ID: "guest-1", FirstName: "Sven"}}
+	updates <- &sweap.GuestUpdate{EventType: sweap.UPDATEGUEST, Value: "not a guest"}
+	updates <- &sweap.GuestUpdate{EventType: sweap.UPDATEGUEST, Value: &sweap.Guest{ID: "guest-2"}}
+	close(updates)
+
+	assert.Nil(t, d.Run(context.Background(), updates))
+
+	assert.Len(t, healthy.payloads, 2)
+	assert.Equal(t, sweap.NEWGUEST, healthy.payloads[0].EventType)
+	assert.Equal(t, "Sven", healthy.payloads[0].Guest.FirstName)
+	assert.Len(t, flaky.payloads, 2)
+	assert.Len(t, broken.payloads, 0)
+
+	m := d.Metrics()
+	assert.Equal(t, sweap.DeliveryMetrics{Delivered: 2, Skipped: 1, LastError: "sweap internal internal error: cannot dispatch update of type string"}, m[healthySrv.URL])
+	assert.Equal(t, int64(1), m[brokenSrv.URL].Skipped)
+	assert.Equal(t, int64(2), m[flakySrv.URL].Delivered)
+	assert.Equal(t, int64(2), m[flakySrv.URL].Retried)
+	assert.Equal(t, int64(0), m[flakySrv.URL].DeadLettered)
+	assert.Equal(t, int64(6), m[brokenSrv.URL].Failed)
+	assert.Equal(t, int64(2), m[brokenSrv.URL].DeadLettered)
+	assert.Contains(t, m[brokenSrv.URL].LastError, "503")
+
+	f, err := os.Open(sweap.DeadLetterFile(dir, brokenSrv.URL))
+	assert.Nil(t, err)
+	defer f.Close()
+	lines := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); lines++ {
+		var entry struct {
+			Endpoint string
+			Attempts int
+			Payload  sweap.WebhookPayload
+		}
+		assert.Nil(t, json.Unmarshal(sc.Bytes(), &entry))
+		assert.Equal(t, brokenSrv.URL, entry.Endpoint)
+		assert.Equal(t, 3, entry.Attempts)
+	}
+	assert.Equal(t, 2, lines)
+	_, err = os.Stat(sweap.DeadLetterFile(dir, healthySrv.URL))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDispatcherCancel(t *testing.T) {
+	broken := &receiver{failures: -1}
+	srv := httptest.NewServer(broken.handler(t, "s"))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := sweap.NewDispatcher([]sweap.WebhookEndpoint{{URL: srv.URL, Secret: "s"}},
+		sweap.DispatcherRetry(sweap.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}),
+		sweap.DispatcherDeadLetterDir(dir))
+
+	updates := make(chan *sweap.GuestUpdate, 1)
+	updates <- &sweap.GuestUpdate{EventType: sweap.NEWGUEST, Value: sweap.Guest{ID: "guest-1"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, d.Run(ctx, updates), context.DeadlineExceeded)
+
+	m := d.Metrics()[srv.URL]
+	assert.Equal(t, int64(1), m.Failed)
+	assert.Equal(t, int64(1), m.DeadLettered)
+	_, err := os.Stat(sweap.DeadLetterFile(dir, srv.URL))
+	assert.Nil(t, err)
+}
+
+func TestDispatcherCancelDeadLettersQueue(t *testing.T) {
+	received, release := make(chan struct{}, 10), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release // hangs until the dispatcher has given up
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	dir := t.TempDir()
+	d := sweap.NewDispatcher([]sweap.WebhookEndpoint{{URL: srv.URL, Secret: "s"}},
+		sweap.DispatcherRetry(sweap.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}),
+		sweap.DispatcherDeadLetterDir(dir))
+
+	updates := make(chan *sweap.GuestUpdate, 3)
+	for i := 1; i <= 3; i++ {
+		updates <- &sweap.GuestUpdate{EventType: sweap.NEWGUEST, Value: sweap.Guest{ID: fmt.Sprintf("guest-%d", i)}}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx, updates) }()
+	<-received
+	for len(updates) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // let the last update reach the queue
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// the delivery in flight and the two still queued
+	assert.Equal(t, int64(3), d.Metrics()[srv.URL].DeadLettered)
+	data, err := os.ReadFile(sweap.DeadLetterFile(dir, srv.URL))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, strings.Count(string(data), "\n"))
+}
@@ -0,0 +1,152 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Sentinel errors matching the Status reported by the Sweap API.
+// Errors returned by the client can be compared with errors.Is:
+//
+//	if errors.Is(err, sweap.ErrDuplicate) { ... }
+var (
+	ErrBadRequest       = errors.New("sweap: bad request")
+	ErrUnauthorized     = errors.New("sweap: unauthorized")
+	ErrWrongCredentials = errors.New("sweap: wrong credentials")
+	ErrAccessDenied     = errors.New("sweap: access denied")
+	ErrNotFound         = errors.New("sweap: not found")
+	ErrDuplicate        = errors.New("sweap: duplicate entity")
+	ErrValidation       = errors.New("sweap: validation failed")
+	ErrServer           = errors.New("sweap: server exception")
+	ErrConflict         = errors.New("sweap: version conflict")
+)
+
+var statusErrors = map[Status]error{
+	BAD_REQUEST:          ErrBadRequest,
+	UNAUTHORIZED:         ErrUnauthorized,
+	WRONG_CREDENTIALS:    ErrWrongCredentials,
+	ACCESS_DENIED:        ErrAccessDenied,
+	NOT_FOUND:            ErrNotFound,
+	DUPLICATE_ENTITY:     ErrDuplicate,
+	VALIDATION_EXCEPTION: ErrValidation,
+	EXCEPTION:            ErrServer,
+}
+
+var httpStatusErrors = map[int]error{
+	http.StatusBadRequest:          ErrBadRequest,
+	http.StatusUnauthorized:        ErrUnauthorized,
+	http.StatusForbidden:           ErrAccessDenied,
+	http.StatusNotFound:            ErrNotFound,
+	http.StatusConflict:            ErrDuplicate,
+	http.StatusUnprocessableEntity: ErrValidation,
+}
+
+// FieldError describes why the value of a single field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is reported for a VALIDATION_EXCEPTION, with per-field details if provided by the server.
+// Retrieve it with errors.As:
+//
+//	var ve *sweap.ValidationError
+//	if errors.As(err, &ve) { ... ve.Fields ... }
+type ValidationError struct {
+	*SweapError
+	Fields []FieldError
+}
+
+func (ve *ValidationError) Error() string {
+	if len(ve.Fields) == 0 {
+		return ve.SweapError.Error()
+	}
+
+	fields := make([]string, 0, len(ve.Fields))
+	for _, f := range ve.Fields {
+		fields = append(fields, fmt.Sprintf("%v: %v", f.Field, f.Message))
+	}
+	return fmt.Sprintf("%v (%v)", ve.SweapError.Error(), strings.Join(fields, ", "))
+}
+
+func (ve *ValidationError) Unwrap() error {
+	return ve.SweapError
+}
+
+// ConflictError is returned if a resource has been changed by someone else since it was read,
+// i.e. its Version on the server differs. It matches ErrConflict.
+type ConflictError struct {
+	Resource       string
+	ID             string
+	Version        int // version of the update
+	CurrentVersion int // version on the server
+}
+
+func (ce *ConflictError) Error() string {
+	return fmt.Sprintf("%v %v: version %d is outdated, current version is %d", ce.Resource, ce.ID, ce.Version, ce.CurrentVersion)
+}
+
+func (ce *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// SweapStatus returns the Status reported by the server, falling back to the error text.
+func (se *SweapError) SweapStatus() Status {
+	if se.Status != "" {
+		return se.Status
+	}
+	return Status(se.Error_)
+}
+
+// Is reports whether the error matches one of the sentinel errors like ErrNotFound.
+func (se *SweapError) Is(target error) bool {
+	if e, ok := statusErrors[se.SweapStatus()]; ok {
+		return e == target
+	}
+	return httpStatusErrors[se.HTTPStatus] == target && target != nil
+}
+
+// As converts the error into a *ValidationError for validation exceptions.
+func (se *SweapError) As(target interface{}) bool {
+	ve, ok := target.(**ValidationError)
+	if !ok || !se.Is(ErrValidation) {
+		return false
+	}
+	*ve = &ValidationError{SweapError: se, Fields: se.Errors}
+	return true
+}
+
+func (se *SweapError) empty() bool {
+	return se.Error_ == "" && se.Code == 0 && se.Message == "" && se.Status == ""
+}
+
+// Is reports whether the HTTP status code matches one of the sentinel errors like ErrNotFound.
+func (t StatusCodeError) Is(target error) bool {
+	if t.Code >= 500 {
+		return target == ErrServer
+	}
+	return httpStatusErrors[t.Code] == target && target != nil
+}
@@ -0,0 +1,89 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSweapErrorIs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/guests/missing":
+			rw.WriteHeader(http.StatusNotFound)
+			rw.Write([]byte(`{"error":"NOT_FOUND","code":4040,"message":"guest not found"}`))
+		case "/guests":
+			rw.WriteHeader(http.StatusConflict)
+			rw.Write([]byte(`{"error":"DUPLICATE_ENTITY","code":4090,"message":"guest exists"}`))
+		case "/categories":
+			rw.WriteHeader(http.StatusConflict)
+		default:
+			rw.WriteHeader(http.StatusForbidden)
+			rw.Write([]byte(`<html>forbidden</html>`))
+		}
+	}))
+	defer server.Close()
+
+	err := getResource(context.Background(), http.DefaultClient, server.URL+"/guests/missing", nil, &Guest{}, discard{})
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrDuplicate))
+	assert.Equal(t, 4040, err.(*SweapError).Code)
+
+	err = postJSON(context.Background(), http.DefaultClient, server.URL+"/guests", []byte(`{}`), &Guest{}, discard{})
+	assert.True(t, errors.Is(err, ErrDuplicate))
+	assert.False(t, errors.Is(err, ErrConflict))
+
+	// a bare 409 on create is a duplicate as well
+	err = postJSON(context.Background(), http.DefaultClient, server.URL+"/categories", []byte(`{}`), &Category{}, discard{})
+	assert.True(t, errors.Is(err, ErrDuplicate))
+
+	err = deleteResource(context.Background(), http.DefaultClient, server.URL+"/other", nil, discard{})
+	assert.True(t, errors.Is(err, ErrAccessDenied))
+	assert.IsType(t, StatusCodeError{}, err)
+}
+
+func TestValidationErrorAs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+		rw.Write([]byte(`{"error":"VALIDATION_EXCEPTION","code":4000,"message":"invalid guest",
+			"errors":[{"field":"email","message":"must be a well-formed email address"}]}`))
+	}))
+	defer server.Close()
+
+	err := putJSON(context.Background(), http.DefaultClient, server.URL, []byte(`{}`), &Guest{}, discard{})
+
+	var ve *ValidationError
+	assert.True(t, errors.As(err, &ve))
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.False(t, errors.Is(err, ErrBadRequest))
+	assert.Equal(t, []FieldError{{Field: "email", Message: "must be a well-formed email address"}}, ve.Fields)
+	assert.Contains(t, ve.Error(), "email: must be a well-formed email address")
+
+	var notFound *ValidationError
+	assert.False(t, errors.As(&SweapError{Error_: string(NOT_FOUND)}, &notFound))
+}
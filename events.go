@@ -22,8 +22,10 @@ package sweap
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 )
 
@@ -101,6 +103,47 @@ func (api *Client) GetEvents() (*Events, error) {
 
 // GetEventsContext will retrieve the complete list of events with a custom context
 func (api *Client) GetEventsContext(ctx context.Context, params EventSearchParameter) (*Events, error) {
+	response, err := api.eventsRequest(ctx, "events", eventSearchValues(params))
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetEventsPaginated will retrieve one page of the list of events
+// GET /events/paginated?[PARAMS]
+func (api *Client) GetEventsPaginated(pp PaginationParameter) (EventPages, error) {
+	return api.GetEventsPaginatedContext(context.Background(), pp, NewEventSearchParameters())
+}
+
+// GetEventsPaginatedContext will retrieve one page of the list of events matching the search parameters with a custom context
+func (api *Client) GetEventsPaginatedContext(ctx context.Context, pages PaginationParameter, params EventSearchParameter) (EventPages, error) {
+	values := eventSearchValues(params)
+	values.Set("page", strconv.Itoa(pages.Page))
+	values.Set("size", strconv.Itoa(pages.Size))
+
+	response := EventPages{}
+	err := api.getMethod(ctx, "events/paginated", values, &response)
+	if err != nil {
+		return EventPages{}, err
+	}
+	return response, nil
+}
+
+// IterateEvents returns an iterator over all events matching the search parameters.
+// Pages of events/paginated are fetched lazily while iterating.
+func (api *Client) IterateEvents(ctx context.Context, params EventSearchParameter, options ...IteratorOptions) *PageIterator[Event] {
+	fetch := func(ctx context.Context, page, size int) ([]Event, int, error) {
+		p, err := api.GetEventsPaginatedContext(ctx, PaginationParameter{Page: page, Size: size}, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		return p.Content, p.Pageable.TotalPages, nil
+	}
+	return NewPageIterator[Event](ctx, fetch, options...)
+}
+
+func eventSearchValues(params EventSearchParameter) url.Values {
 	values := url.Values{}
 
 	if params.Id != "" {
@@ -139,11 +182,7 @@ func (api *Client) GetEventsContext(ctx context.Context, params EventSearchParam
 		values.Add("externalId", string(params.ExternalID))
 	}
 
-	response, err := api.eventsRequest(ctx, "events", values)
-	if err != nil {
-		return nil, err
-	}
-	return response, nil
+	return values
 }
 
 // GetEvents will retrieve the event with the given ID
@@ -212,6 +251,122 @@ func (api *Client) eventRequest(ctx context.Context, path string, values url.Val
 	return response, nil
 }
 
+// CreateEvent creates an Event and returns it containing it's unique ID
+// POST /events
+func (api *Client) CreateEvent(e Event) (*Event, error) {
+	return api.CreateEventContext(context.Background(), e)
+}
+
+// CreateEventContext creates an Event with a custom context.
+// The event is validated before it is sent, see Event.Validate. A new event may only be created as DRAFT or ACTIVE.
+func (api *Client) CreateEventContext(ctx context.Context, e Event) (*Event, error) {
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+	if EventState(e.State) == CLOSED {
+		return nil, SweapLibraryError{"an event cannot be created as " + string(CLOSED)}
+	}
+
+	request, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Event)
+	if err := postJSON(ctx, api.httpclient, api.endpoint+"events", request, resp, api); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// UpdateEvent updates the event with the ID of the given event
+// PUT /events/{ID}
+func (api *Client) UpdateEvent(e Event) (*Event, error) {
+	return api.UpdateEventContext(context.Background(), e)
+}
+
+// UpdateEventContext updates the event with the ID of the given event with a custom context.
+// The event is validated before it is sent, see Event.Validate. If the state changes,
+// the current event is retrieved first to check that the transition is allowed:
+// DRAFT -> ACTIVE -> CLOSED. With OptionOptimisticLocking it returns a *ConflictError
+// if the Version of the event has changed on the server.
+func (api *Client) UpdateEventContext(ctx context.Context, e Event) (*Event, error) {
+	if e.ID == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+	if err := e.Validate(); err != nil {
+		return nil, err
+	}
+
+	if e.State != "" || api.checkVersion {
+		current, err := api.GetEventByIdContext(ctx, e.ID)
+		if err != nil {
+			return nil, err
+		}
+		if api.checkVersion && current.Version != e.Version {
+			return nil, &ConflictError{Resource: "event", ID: e.ID, Version: e.Version, CurrentVersion: current.Version}
+		}
+		if e.State != "" && !EventState(current.State).CanTransitionTo(EventState(e.State)) {
+			return nil, SweapLibraryError{fmt.Sprintf("event state cannot change from %v to %v", current.State, e.State)}
+		}
+	}
+
+	request, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(Event)
+	api.fieldDefs.forget(e.ID)
+	if err := putJSON(ctx, api.httpclient, api.endpoint+"events/"+e.ID, request, resp, api); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DeleteEvent will delete the event with the given ID. If event is not found error will be returned
+// DELETE /events/{ID}
+func (api *Client) DeleteEvent(id string) error {
+	return api.DeleteEventContext(context.Background(), id)
+}
+
+// DeleteEventContext will delete the event with the given ID with a custom context.
+func (api *Client) DeleteEventContext(ctx context.Context, id string) error {
+	if id == "" {
+		return SweapLibraryError{"no event ID given"}
+	}
+
+	return deleteResource(ctx, api.httpclient, api.endpoint+"events/"+id, nil, api)
+}
+
+// Validate checks the event before it is created or updated: the name must be given,
+// the end date must be after the start date, the ZoneId must be an IANA time zone
+// and AttendanceMode and State must be known values, if set.
+func (e Event) Validate() error {
+	if e.Name == "" {
+		return SweapLibraryError{"no event name given"}
+	}
+	if !e.StartDate.IsZero() && !e.EndDate.IsZero() && !e.EndDate.After(e.StartDate) {
+		return SweapLibraryError{fmt.Sprintf("event ends (%v) before or when it starts (%v)", e.EndDate, e.StartDate)}
+	}
+	if e.ZoneId != "" {
+		if _, err := time.LoadLocation(e.ZoneId); err != nil || e.ZoneId == "Local" {
+			return SweapLibraryError{fmt.Sprintf("unknown time zone %q", e.ZoneId)}
+		}
+	}
+	switch e.AttendanceMode {
+	case "", OFFLINE, ONLINE, MIXED:
+	default:
+		return SweapLibraryError{fmt.Sprintf("unknown attendance mode %q", e.AttendanceMode)}
+	}
+	switch EventState(e.State) {
+	case "", DRAFT, ACTIVE, CLOSED:
+	default:
+		return SweapLibraryError{fmt.Sprintf("unknown event state %q", e.State)}
+	}
+	return nil
+}
+
 func (e Event) String() string {
 	return e.Name + ":" + e.ID
 }
@@ -0,0 +1,124 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func TestEventLifecycle(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	start := time.Date(2023, 9, 1, 18, 0, 0, 0, time.UTC)
+	e, err := api.CreateEvent(sweap.Event{
+		Name:           "Autumn Reception",
+		StartDate:      start,
+		EndDate:        start.Add(4 * time.Hour),
+		ZoneId:         "Europe/Berlin",
+		AttendanceMode: sweap.OFFLINE,
+		CustomFieldDefinitions: []sweap.CustomFieldDefinitions{
+			{Name: "company", Type: "TEXT"},
+		},
+	})
+	assert.Nil(t, err)
+	assert.NotEmpty(t, e.ID)
+	assert.Equal(t, string(sweap.DRAFT), e.State)
+	assert.Equal(t, "company", e.CustomFieldDefinitions[0].Name)
+
+	e.State = string(sweap.ACTIVE)
+	e.Name = "Autumn Reception 2023"
+	e, err = api.UpdateEvent(*e)
+	assert.Nil(t, err)
+	stored, _ := srv.Event(e.ID)
+	assert.Equal(t, "Autumn Reception 2023", stored.Name)
+	assert.Equal(t, string(sweap.ACTIVE), stored.State)
+
+	e.State = string(sweap.DRAFT)
+	_, err = api.UpdateEvent(*e)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	stored, _ = srv.Event(e.ID)
+	assert.Equal(t, string(sweap.ACTIVE), stored.State)
+
+	e.State = string(sweap.CLOSED)
+	_, err = api.UpdateEvent(*e)
+	assert.Nil(t, err)
+
+	assert.Nil(t, api.DeleteEvent(e.ID))
+	_, err = api.GetEventById(e.ID)
+	assert.True(t, errors.Is(err, sweap.ErrNotFound))
+	assert.True(t, errors.Is(api.DeleteEvent(e.ID), sweap.ErrNotFound))
+}
+
+func TestEventValidation(t *testing.T) {
+	start := time.Date(2023, 9, 1, 18, 0, 0, 0, time.UTC)
+	valid := sweap.Event{Name: "Event", StartDate: start, EndDate: start.Add(2 * time.Hour), ZoneId: "America/New_York", State: string(sweap.DRAFT)}
+	assert.Nil(t, valid.Validate())
+
+	tests := []struct {
+		name   string
+		modify func(e *sweap.Event)
+	}{
+		{"no name", func(e *sweap.Event) { e.Name = "" }},
+		{"end before start", func(e *sweap.Event) { e.EndDate = start.Add(-time.Minute) }},
+		{"end equals start", func(e *sweap.Event) { e.EndDate = start }},
+		{"unknown zone", func(e *sweap.Event) { e.ZoneId = "Mars/Olympus_Mons" }},
+		{"local zone", func(e *sweap.Event) { e.ZoneId = "Local" }},
+		{"attendance mode", func(e *sweap.Event) { e.AttendanceMode = "HYBRID" }},
+		{"state", func(e *sweap.Event) { e.State = "ARCHIVED" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid
+			tt.modify(&e)
+			assert.ErrorAs(t, e.Validate(), &sweap.SweapLibraryError{})
+		})
+	}
+
+	srv := sweaptest.NewServer(sweaptest.Seed{})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	_, err := api.CreateEvent(sweap.Event{Name: "Closed", State: string(sweap.CLOSED)})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	_, err = api.UpdateEvent(sweap.Event{Name: "No ID"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.ErrorAs(t, api.DeleteEvent(""), &sweap.SweapLibraryError{})
+	assert.Empty(t, srv.Requests())
+}
+
+func TestEventStateTransitions(t *testing.T) {
+	assert.True(t, sweap.DRAFT.CanTransitionTo(sweap.DRAFT))
+	assert.True(t, sweap.DRAFT.CanTransitionTo(sweap.ACTIVE))
+	assert.True(t, sweap.ACTIVE.CanTransitionTo(sweap.CLOSED))
+	assert.False(t, sweap.DRAFT.CanTransitionTo(sweap.CLOSED))
+	assert.False(t, sweap.ACTIVE.CanTransitionTo(sweap.DRAFT))
+	assert.False(t, sweap.CLOSED.CanTransitionTo(sweap.ACTIVE))
+}
@@ -16,8 +16,7 @@ import (
 const numWorkers = 200
 const batchSize = 50
 const numGuests = 120000
-const intraWorkerDelay = 200
-const interRowDelay = 1
+const requestsPerSecond = 50
 
 var eventName = "Load Testing 4"
 
@@ -31,20 +30,19 @@ type processed struct {
 }
 
 type result struct {
-	worker           int
-	batchSize        int
-	intraWorkerDelay int
-	interRowDelay    int
-	guestCreated     int
-	eventName        string
-	executionTime    int
-	numRows          int
-	peopleCount      int
-	commonName       string
-	commonNameCount  int
-	numErrors        int
-	avgCps           float32
-	errors           map[string]int
+	worker          int
+	batchSize       int
+	rateLimit       int
+	guestCreated    int
+	eventName       string
+	executionTime   int
+	numRows         int
+	peopleCount     int
+	commonName      string
+	commonNameCount int
+	numErrors       int
+	avgCps          float32
+	errors          map[string]int
 }
 
 func (r *result) String() string {
@@ -53,8 +51,7 @@ func (r *result) String() string {
 	sb.WriteString("Result:\n")
 	sb.WriteString(fmt.Sprintf("  Worker: %v\n", r.worker))
 	sb.WriteString(fmt.Sprintf("  Batch Size: %d\n", r.batchSize))
-	sb.WriteString(fmt.Sprintf("  Delay btw Creates (in ms): %d\n", r.interRowDelay))
-	sb.WriteString(fmt.Sprintf("  Delay btw Batches (in ms): %d\n", r.intraWorkerDelay))
+	sb.WriteString(fmt.Sprintf("  Rate Limit (in req/s): %d\n", r.rateLimit))
 	sb.WriteString(fmt.Sprintf("  Guest Created: %d\n", r.guestCreated))
 	sb.WriteString(fmt.Sprintf("  Event Name: %s\n", r.eventName))
 	sb.WriteString(fmt.Sprintf("  Num Rows: %d\n", r.numRows))
@@ -97,7 +94,7 @@ func NewLogger() *log.Logger {
 func main() {
 
 	// ClearGuest List
-	api, _ := sweap.New("", "", sweap.OptionDebug(false), sweap.OptionUseStagingEnv(), sweap.OptionEnvFile("../../.stageing-env"))
+	api, _ := sweap.New("", "", sweap.OptionDebug(false), sweap.OptionUseStagingEnv(), sweap.OptionRateLimit(requestsPerSecond, numWorkers), sweap.OptionEnvFile("../../.stageing-env"))
 	search := sweap.EventSearchParameter{
 		Name: eventName,
 	}
@@ -125,13 +122,12 @@ func main() {
 	eventID := (*events)[0].ID
 
 	res := result{
-		worker:           numWorkers,
-		batchSize:        batchSize,
-		interRowDelay:    interRowDelay,
-		intraWorkerDelay: intraWorkerDelay,
-		guestCreated:     numGuests,
-		eventName:        eventName,
-		errors:           make(map[string]int),
+		worker:       numWorkers,
+		batchSize:    batchSize,
+		rateLimit:    requestsPerSecond,
+		guestCreated: numGuests,
+		eventName:    eventName,
+		errors:       make(map[string]int),
 	}
 
 	// create a main context, and call cancel at the end, to ensure all our
@@ -34,7 +34,6 @@ func createGuestsWorker(ctx context.Context, rowBatch <-chan []sweap.Guest, api
 			startGuests := p.numRows
 			for _, eachGuest := range rowBatch {
 				_, err := api.CreateGuest(eachGuest)
-				time.Sleep(interRowDelay * time.Millisecond)
 
 				if err != nil {
 					p.numErrors++
@@ -47,9 +46,6 @@ func createGuestsWorker(ctx context.Context, rowBatch <-chan []sweap.Guest, api
 				p.numRows++
 			}
 			cLog.Infof("%v-%v/%v -> AvgGCps[actual/mean]: %v/%v\n", p.numRows-startGuests, j*batchSize, guestToCreatePerBatch, float32(float32(p.numRows-startGuests)/(float32(time.Since(currentBatchstartTime).Milliseconds()/1000))), float32(float32(p.numRows)/(float32(time.Since(p.startTime).Milliseconds()/1000))))
-
-			time.Sleep(intraWorkerDelay * time.Millisecond)
-
 		}
 		out <- p
 	}()
@@ -67,55 +63,35 @@ func createBulkImportWorker(ctx context.Context, rowBatch <-chan []sweap.Guest,
 		defer close(out)
 
 		p := processed{
-			errors: make(map[string]int),
-		}
-		j := 0
-
-		gbiNew := sweap.GuestBulkImport{
-			ID:                     "",
-			Version:                0,
-			ExternalID:             "1234",
-			Name:                   fmt.Sprintf("Worker %v created", workerNum),
-			Guests:                 nil,
-			EventId:                eventID,
-			CustomFieldDefinitions: []sweap.CustomFieldDefinitions{},
-		}
-
-		gbi, err := api.CreateGuestBulkImportObject(gbiNew)
-		if err != nil {
-			cLog.Warnf("%s\n", err)
-			return
+			errors:    make(map[string]int),
+			startTime: time.Now(),
 		}
-		cLog.Debug(pp(gbi))
 
+		guests := sweap.Guests{}
 		for rowBatch := range rowBatch {
-			j++
-
-			gbiID := gbi.ID
-			err = api.BulkImportUpdateBatch(gbiID, rowBatch)
-			cLog.Infof("Uploading batch %v for worker %v", j, workerNum)
-			if err != nil {
-				p.numErrors++
-				p.errors[err.Error()] = p.errors[err.Error()] + 1
-				cLog.Warnf("[%v] %v", workerNum, err)
-			}
-
 			for _, eachGuest := range rowBatch {
 				firstName, lastName := processRow(eachGuest)
 				p.lastNames = append(p.lastNames, lastName)
 				p.firstNames = append(p.firstNames, firstName)
 				p.numRows++
 			}
-
+			guests = append(guests, rowBatch...)
 		}
 
-		api.BulkImportFinishUpload(gbi.ID)
-		cLog.Infof("Uploading for %v/%v", j, workerNum)
-
+		result, err := api.ImportGuests(ctx, eventID, guests, sweap.ImportOptions{
+			Name:       fmt.Sprintf("Worker %v created", workerNum),
+			ExternalID: "1234",
+			BatchSize:  batchSize,
+			Progress: func(ip sweap.ImportProgress) {
+				cLog.Infof("%v: %v/%v batches uploaded", ip.State, ip.BatchesUploaded, ip.Batches)
+			},
+		})
 		if err != nil {
 			p.numErrors++
 			p.errors[err.Error()] = p.errors[err.Error()] + 1
 			cLog.Warnf("[%v] %v", workerNum, err)
+		} else {
+			cLog.Debug(pp(result.BulkImport))
 		}
 
 		out <- p
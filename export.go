@@ -0,0 +1,254 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ExportedGuest is a guest as written by ExportGuestsCSV and ExportGuestsJSONL: the category name is
+// resolved from CategoryID, companions carry the name of the guest they accompany and custom fields
+// are keyed by the names of their definitions.
+type ExportedGuest struct {
+	ID              string            `json:"id"`
+	ExternalID      string            `json:"externalId,omitempty"`
+	FirstName       string            `json:"firstName"`
+	LastName        string            `json:"lastName"`
+	Email           string            `json:"email,omitempty"`
+	EntourageCount  int               `json:"entourageCount"`
+	InvitationState InvitationState   `json:"invitationState,omitempty"`
+	AttendanceState AttendanceState   `json:"attendanceState,omitempty"`
+	CategoryID      string            `json:"categoryId,omitempty"`
+	Category        string            `json:"category,omitempty"`
+	ParentGuestID   string            `json:"parentGuestId,omitempty"`
+	CompanionOf     string            `json:"companionOf,omitempty"` // name of the parent guest
+	TicketID        string            `json:"ticketId,omitempty"`
+	Comment         string            `json:"comment,omitempty"`
+	CustomFields    map[string]string `json:"customFields,omitempty"`
+}
+
+// exportColumns are the CSV columns preceding the custom fields.
+var exportColumns = []string{
+	"id", "externalId", "firstName", "lastName", "email", "entourageCount", "invitationState", "attendanceState",
+	"categoryId", "category", "parentGuestId", "companionOf", "ticketId", "comment",
+}
+
+func (e ExportedGuest) record(defs []CustomFieldDefinitions) []string {
+	r := []string{
+		e.ID, e.ExternalID, e.FirstName, e.LastName, e.Email, strconv.Itoa(e.EntourageCount), string(e.InvitationState), string(e.AttendanceState),
+		e.CategoryID, e.Category, e.ParentGuestID, e.CompanionOf, e.TicketID, e.Comment,
+	}
+	for _, d := range defs {
+		r = append(r, e.CustomFields[d.Name])
+	}
+	return r
+}
+
+// ExportGuestsCSV writes the guests of an event matching the search parameters as CSV and returns
+// the number of exported guests. After the fixed columns follows one column per custom field
+// definition of the event, in SortIndex order, headed by the name of the field.
+// Guests are fetched page by page while writing, so large events can be exported to a file or
+// HTTP response without holding them in memory. The name of the parent of a companion is taken
+// from the guests exported before, only parents not among them are retrieved.
+func (api *Client) ExportGuestsCSV(ctx context.Context, eventId string, w io.Writer, params GuestSearchParameter, options ...IteratorOptions) (int, error) {
+	x, err := api.newGuestExporter(ctx, eventId)
+	if err != nil {
+		return 0, err
+	}
+
+	cw := csv.NewWriter(w)
+	header := append([]string{}, exportColumns...)
+	for _, d := range x.defs {
+		header = append(header, d.Name)
+	}
+	if err := cw.Write(header); err != nil {
+		return 0, err
+	}
+
+	n, err := x.export(api.IterateGuests(ctx, eventId, params, options...), func(e ExportedGuest) error {
+		return cw.Write(e.record(x.defs))
+	})
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return n, err
+}
+
+// ExportGuestsJSONL writes the guests of an event matching the search parameters as JSON Lines,
+// one ExportedGuest per line, and returns the number of exported guests.
+// Like ExportGuestsCSV guests are fetched page by page while writing.
+func (api *Client) ExportGuestsJSONL(ctx context.Context, eventId string, w io.Writer, params GuestSearchParameter, options ...IteratorOptions) (int, error) {
+	x, err := api.newGuestExporter(ctx, eventId)
+	if err != nil {
+		return 0, err
+	}
+	enc := json.NewEncoder(w)
+	return x.export(api.IterateGuests(ctx, eventId, params, options...), func(e ExportedGuest) error {
+		return enc.Encode(e)
+	})
+}
+
+// guestExporter converts guests into ExportedGuests.
+type guestExporter struct {
+	api        *Client
+	ctx        context.Context
+	defs       []CustomFieldDefinitions // sorted by SortIndex
+	categories map[string]string        // names by ID
+	names      *nameCache               // names of exported and parent guests by ID
+}
+
+// exportNameCacheSize bounds the guest names remembered during an export to look up parents.
+const exportNameCacheSize = 10000
+
+// nameCache holds the names of the most recently added guests.
+type nameCache struct {
+	names map[string]string
+	order []string // ring of the IDs in names, oldest at next
+	next  int
+}
+
+func newNameCache(size int) *nameCache {
+	return &nameCache{names: map[string]string{}, order: make([]string, 0, size)}
+}
+
+func (c *nameCache) get(id string) (string, bool) {
+	name, ok := c.names[id]
+	return name, ok
+}
+
+func (c *nameCache) put(id, name string) {
+	if _, ok := c.names[id]; ok {
+		c.names[id] = name
+		return
+	}
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, id)
+	} else {
+		delete(c.names, c.order[c.next])
+		c.order[c.next] = id
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.names[id] = name
+}
+
+func (api *Client) newGuestExporter(ctx context.Context, eventId string) (*guestExporter, error) {
+	if eventId == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+	event, err := api.GetEventByIdContext(ctx, eventId)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := api.GetCategoriesContext(ctx, eventId, NewCategorySearchParameter())
+	if err != nil {
+		return nil, err
+	}
+
+	x := &guestExporter{api: api, ctx: ctx, categories: map[string]string{}, names: newNameCache(exportNameCacheSize)}
+	x.defs = append(x.defs, event.CustomFieldDefinitions...)
+	sort.SliceStable(x.defs, func(i, j int) bool { return x.defs[i].SortIndex < x.defs[j].SortIndex })
+	for _, c := range *categories {
+		x.categories[c.ID] = c.Name
+	}
+	return x, nil
+}
+
+// export converts and writes the guests of the iterator.
+func (x *guestExporter) export(it *PageIterator[Guest], write func(ExportedGuest) error) (int, error) {
+	n := 0
+	for it.Next() {
+		e, err := x.convert(it.Value())
+		if err != nil {
+			return n, err
+		}
+		if err := write(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, it.Err()
+}
+
+func (x *guestExporter) convert(g Guest) (ExportedGuest, error) {
+	e := ExportedGuest{
+		ID:              g.ID,
+		ExternalID:      externalIDString(g.ExternalID),
+		FirstName:       g.FirstName,
+		LastName:        g.LastName,
+		Email:           g.Email,
+		EntourageCount:  g.EntourageCount,
+		InvitationState: g.InvitationState,
+		AttendanceState: g.AttendanceState,
+		CategoryID:      g.CategoryID,
+		Category:        x.categories[g.CategoryID],
+		ParentGuestID:   g.ParentGuestID,
+		TicketID:        g.TicketID,
+	}
+	if g.Comment != nil {
+		e.Comment = fmt.Sprint(g.Comment)
+	}
+	for _, d := range x.defs {
+		if v, ok := g.CustomFields[d.ID]; ok {
+			if e.CustomFields == nil {
+				e.CustomFields = map[string]string{}
+			}
+			e.CustomFields[d.Name] = v
+		}
+	}
+
+	// companions usually follow their parent, which then needs not be retrieved
+	x.names.put(g.ID, guestName(g))
+	if g.ParentGuestID != "" {
+		name, ok := x.names.get(g.ParentGuestID)
+		if !ok {
+			parent, err := x.api.GetGuestByIdContext(x.ctx, g.ParentGuestID)
+			switch {
+			case errors.Is(err, ErrNotFound):
+			case err != nil:
+				return e, err
+			default:
+				name = guestName(*parent)
+			}
+			x.names.put(g.ParentGuestID, name)
+		}
+		e.CompanionOf = name
+	}
+	return e, nil
+}
+
+func guestName(g Guest) string {
+	switch {
+	case g.FirstName == "":
+		return g.LastName
+	case g.LastName == "":
+		return g.FirstName
+	}
+	return g.FirstName + " " + g.LastName
+}
@@ -0,0 +1,132 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func exportSeed() sweaptest.Seed {
+	return sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Export", CustomFieldDefinitions: []sweap.CustomFieldDefinitions{
+			{ID: "cf-menu", Name: "Menu", Type: "TEXT", SortIndex: 2},
+			{ID: "cf-seats", Name: "Seats", Type: "NUMBER", SortIndex: 1},
+		}}},
+		Categories: sweap.Categories{{ID: "cat-vip", Name: "VIP", EventID: "event-1"}},
+		Guests: sweap.Guests{
+			{ID: "g-1", EventID: "event-1", ExternalID: 7.0, FirstName: "Anna", LastName: "Adams", Email: "anna@example.com",
+				CategoryID: "cat-vip", InvitationState: sweap.ACCEPTED, AttendanceState: sweap.PRESENT, TicketID: "T-1", CustomFields: sweap.CustomFields{"cf-menu": "vegan", "cf-seats": "2"}},
+			{ID: "g-2", EventID: "event-1", FirstName: "Ben", LastName: "Berg", ParentGuestID: "g-1", Comment: "plus one, \"late\"",
+				InvitationState: sweap.NO_REPLY, AttendanceState: sweap.NONEATTENDANCE, TicketID: "T-2"},
+			{ID: "g-3", EventID: "event-1", FirstName: "Carl", ParentGuestID: "g-1", CustomFields: sweap.CustomFields{"cf-unknown": "x"},
+				InvitationState: sweap.NO_REPLY, AttendanceState: sweap.NONEATTENDANCE, TicketID: "T-3"},
+			{ID: "g-4", EventID: "event-2", FirstName: "Dora"},
+		},
+	}
+}
+
+func TestExportGuestsCSV(t *testing.T) {
+	srv := sweaptest.NewServer(exportSeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	var buf bytes.Buffer
+	n, err := api.ExportGuestsCSV(context.Background(), "event-1", &buf, sweap.NewGuestSearchParameters(), sweap.IteratorPageSize(2))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, n)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"id", "externalId", "firstName", "lastName", "email", "entourageCount", "invitationState", "attendanceState",
+			"categoryId", "category", "parentGuestId", "companionOf", "ticketId", "comment", "Seats", "Menu"},
+		{"g-1", "7", "Anna", "Adams", "anna@example.com", "0", "ACCEPTED", "PRESENT", "cat-vip", "VIP", "", "", "T-1", "", "2", "vegan"},
+		{"g-2", "", "Ben", "Berg", "", "0", "NO_REPLY", "NONE", "", "", "g-1", "Anna Adams", "T-2", "plus one, \"late\"", "", ""},
+		{"g-3", "", "Carl", "", "", "0", "NO_REPLY", "NONE", "", "", "g-1", "Anna Adams", "T-3", "", "", ""},
+	}, records)
+
+	// the parent of the companions has been exported before and is not read
+	parentGets := func() int {
+		gets := 0
+		for _, r := range srv.Requests() {
+			if r.Path == "/guests/g-1" {
+				gets++
+			}
+		}
+		return gets
+	}
+	assert.Equal(t, 0, parentGets())
+
+	// filters apply, the parent left out is read
+	buf.Reset()
+	n, err = api.ExportGuestsCSV(context.Background(), "event-1", &buf, sweap.GuestSearchParameter{FirstName: "anna"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	buf.Reset()
+	n, err = api.ExportGuestsCSV(context.Background(), "event-1", &buf, sweap.GuestSearchParameter{FirstName: "ben"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, n)
+	assert.Contains(t, buf.String(), "Anna Adams")
+	assert.Equal(t, 1, parentGets())
+
+	_, err = api.ExportGuestsCSV(context.Background(), "", &buf, sweap.NewGuestSearchParameters())
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
+
+func TestExportGuestsJSONL(t *testing.T) {
+	seed := exportSeed()
+	seed.Guests = append(seed.Guests, sweap.Guest{ID: "g-5", EventID: "event-1", FirstName: "Emil", ParentGuestID: "gone"})
+	srv := sweaptest.NewServer(seed)
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	var buf bytes.Buffer
+	n, err := api.ExportGuestsJSONL(context.Background(), "event-1", &buf, sweap.NewGuestSearchParameters())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, n)
+
+	var exported []sweap.ExportedGuest
+	lines := bufio.NewScanner(&buf)
+	for lines.Scan() {
+		var e sweap.ExportedGuest
+		assert.Nil(t, json.Unmarshal(lines.Bytes(), &e))
+		exported = append(exported, e)
+	}
+	assert.Len(t, exported, 4)
+	assert.Equal(t, sweap.ExportedGuest{ID: "g-1", ExternalID: "7", FirstName: "Anna", LastName: "Adams", Email: "anna@example.com",
+		InvitationState: sweap.ACCEPTED, AttendanceState: sweap.PRESENT, CategoryID: "cat-vip", Category: "VIP", TicketID: "T-1",
+		CustomFields: map[string]string{"Menu": "vegan", "Seats": "2"}}, exported[0])
+	assert.Equal(t, "Anna Adams", exported[1].CompanionOf)
+	assert.Nil(t, exported[2].CustomFields, "values of unknown custom fields are not exported")
+	assert.Equal(t, "gone", exported[3].ParentGuestID)
+	assert.Empty(t, exported[3].CompanionOf, "missing parents are not an error")
+}
@@ -0,0 +1,270 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type FieldChangeKind string
+
+const (
+	FIELDADDED   FieldChangeKind = "ADD"
+	FIELDUPDATED FieldChangeKind = "UPDATE"
+	FIELDREMOVED FieldChangeKind = "REMOVE"
+)
+
+// FieldChange is a difference between two lists of custom field definitions.
+// Old is empty for added fields, New is empty for removed fields.
+type FieldChange struct {
+	Kind FieldChangeKind
+	Old  CustomFieldDefinitions
+	New  CustomFieldDefinitions
+}
+
+func (c FieldChange) String() string {
+	switch c.Kind {
+	case FIELDADDED:
+		return fmt.Sprintf("add %v (%v)", c.New.Name, c.New.Type)
+	case FIELDREMOVED:
+		return fmt.Sprintf("remove %v (%v)", c.Old.Name, c.Old.ID)
+	}
+	return fmt.Sprintf("update %v (%v)", c.New.Name, c.Old.ID)
+}
+
+// DiffCustomFieldDefinitions compares the actual definitions with the desired ones.
+// Desired definitions are matched by ID or, without an ID, by case insensitive name.
+// Actual definitions without a desired counterpart are removed. A desired SortIndex of 0
+// keeps the position of an existing field and places a new field last.
+func DiffCustomFieldDefinitions(actual, desired []CustomFieldDefinitions) []FieldChange {
+	matched := make([]bool, len(actual))
+	var changes []FieldChange
+
+	last := 0
+	for _, a := range actual {
+		if a.SortIndex > last {
+			last = a.SortIndex
+		}
+	}
+
+	for _, d := range desired {
+		i := matchDefinition(actual, matched, d)
+		if i < 0 {
+			if d.SortIndex == 0 {
+				last++
+				d.SortIndex = last
+			}
+			changes = append(changes, FieldChange{Kind: FIELDADDED, New: d})
+			continue
+		}
+		matched[i] = true
+		d.ID = actual[i].ID
+		if d.SortIndex == 0 {
+			d.SortIndex = actual[i].SortIndex
+		}
+		if !sameDefinition(actual[i], d) {
+			changes = append(changes, FieldChange{Kind: FIELDUPDATED, Old: actual[i], New: d})
+		}
+	}
+	for i, a := range actual {
+		if !matched[i] {
+			changes = append(changes, FieldChange{Kind: FIELDREMOVED, Old: a})
+		}
+	}
+	return changes
+}
+
+func matchDefinition(actual []CustomFieldDefinitions, matched []bool, d CustomFieldDefinitions) int {
+	for i, a := range actual {
+		if matched[i] {
+			continue
+		}
+		if d.ID != "" && a.ID == d.ID || d.ID == "" && strings.EqualFold(a.Name, d.Name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameDefinition compares two definitions, Options and GroupName by their JSON form
+// as they are decoded into generic values.
+func sameDefinition(a, b CustomFieldDefinitions) bool {
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// ApplyFieldChanges returns the definitions with the changes applied, ordered by SortIndex.
+// Changed definitions are found by ID or, if they have no ID yet as in a new guest bulk import, by content.
+func ApplyFieldChanges(defs []CustomFieldDefinitions, changes []FieldChange) []CustomFieldDefinitions {
+	result := append([]CustomFieldDefinitions(nil), defs...)
+	changed := make([]bool, len(result)) // a definition is only changed once
+	for _, c := range changes {
+		switch c.Kind {
+		case FIELDADDED:
+			result = append(result, c.New)
+			changed = append(changed, true)
+		case FIELDUPDATED:
+			if i := findDefinition(result, changed, c.Old); i >= 0 {
+				result[i] = c.New
+				changed[i] = true
+			}
+		case FIELDREMOVED:
+			if i := findDefinition(result, changed, c.Old); i >= 0 {
+				result = append(result[:i], result[i+1:]...)
+				changed = append(changed[:i], changed[i+1:]...)
+			}
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].SortIndex < result[j].SortIndex })
+	return result
+}
+
+// findDefinition returns the position of the unchanged definition old, matched by ID if it has one.
+func findDefinition(defs []CustomFieldDefinitions, changed []bool, old CustomFieldDefinitions) int {
+	for i, d := range defs {
+		if changed[i] {
+			continue
+		}
+		if old.ID != "" && d.ID == old.ID || old.ID == "" && sameDefinition(d, old) {
+			return i
+		}
+	}
+	return -1
+}
+
+func indexOfDefinition(defs []CustomFieldDefinitions, id string) int {
+	for i, d := range defs {
+		if d.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// AddCustomFieldDefinition appends a definition. Its name must be unique and, if not set,
+// its SortIndex is placed after the existing definitions.
+func AddCustomFieldDefinition(defs []CustomFieldDefinitions, d CustomFieldDefinitions) ([]CustomFieldDefinitions, error) {
+	if d.Name == "" {
+		return nil, SweapLibraryError{"no custom field name given"}
+	}
+	last := 0
+	for _, e := range defs {
+		if strings.EqualFold(e.Name, d.Name) {
+			return nil, SweapLibraryError{fmt.Sprintf("custom field %v already exists", d.Name)}
+		}
+		if e.SortIndex > last {
+			last = e.SortIndex
+		}
+	}
+	if d.SortIndex == 0 {
+		d.SortIndex = last + 1
+	}
+	return append(append([]CustomFieldDefinitions(nil), defs...), d), nil
+}
+
+// RenameCustomFieldDefinition changes the name of the definition with the given ID.
+func RenameCustomFieldDefinition(defs []CustomFieldDefinitions, id, name string) ([]CustomFieldDefinitions, error) {
+	i := indexOfDefinition(defs, id)
+	if i < 0 {
+		return nil, SweapLibraryError{fmt.Sprintf("unknown custom field %v", id)}
+	}
+	for _, e := range defs {
+		if e.ID != id && strings.EqualFold(e.Name, name) {
+			return nil, SweapLibraryError{fmt.Sprintf("custom field %v already exists", name)}
+		}
+	}
+	result := append([]CustomFieldDefinitions(nil), defs...)
+	result[i].Name = name
+	return result, nil
+}
+
+// RemoveCustomFieldDefinition removes the definition with the given ID.
+func RemoveCustomFieldDefinition(defs []CustomFieldDefinitions, id string) ([]CustomFieldDefinitions, error) {
+	i := indexOfDefinition(defs, id)
+	if i < 0 {
+		return nil, SweapLibraryError{fmt.Sprintf("unknown custom field %v", id)}
+	}
+	result := append([]CustomFieldDefinitions(nil), defs[:i]...)
+	return append(result, defs[i+1:]...), nil
+}
+
+// ReorderCustomFieldDefinitions rewrites the SortIndex, starting with 1. The definitions listed
+// in ids come first, in the given order; the remaining ones follow in their previous order.
+func ReorderCustomFieldDefinitions(defs []CustomFieldDefinitions, ids []string) ([]CustomFieldDefinitions, error) {
+	rest := append([]CustomFieldDefinitions(nil), defs...)
+	sort.SliceStable(rest, func(i, j int) bool { return rest[i].SortIndex < rest[j].SortIndex })
+
+	result := make([]CustomFieldDefinitions, 0, len(defs))
+	for _, id := range ids {
+		i := indexOfDefinition(rest, id)
+		if i < 0 {
+			return nil, SweapLibraryError{fmt.Sprintf("unknown or duplicate custom field %v", id)}
+		}
+		result = append(result, rest[i])
+		rest = append(rest[:i], rest[i+1:]...)
+	}
+	result = append(result, rest...)
+	for i := range result {
+		result[i].SortIndex = i + 1
+	}
+	return result, nil
+}
+
+// SyncCustomFieldDefinitions changes the custom field definitions of an event to the desired ones,
+// see DiffCustomFieldDefinitions. The event is only updated if there are changes, which are returned.
+func (api *Client) SyncCustomFieldDefinitions(eventId string, desired []CustomFieldDefinitions) ([]FieldChange, error) {
+	return api.SyncCustomFieldDefinitionsContext(context.Background(), eventId, desired)
+}
+
+// SyncCustomFieldDefinitionsContext changes the custom field definitions of an event with a custom context.
+func (api *Client) SyncCustomFieldDefinitionsContext(ctx context.Context, eventId string, desired []CustomFieldDefinitions) ([]FieldChange, error) {
+	e, err := api.GetEventByIdContext(ctx, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := DiffCustomFieldDefinitions(e.CustomFieldDefinitions, desired)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	e.CustomFieldDefinitions = ApplyFieldChanges(e.CustomFieldDefinitions, changes)
+	if _, err := api.UpdateEventContext(ctx, *e); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// SyncCustomFieldDefinitions changes the custom field definitions of the bulk import to the
+// desired ones before it is created, see DiffCustomFieldDefinitions, and returns the changes.
+func (gbi *GuestBulkImport) SyncCustomFieldDefinitions(desired []CustomFieldDefinitions) []FieldChange {
+	changes := DiffCustomFieldDefinitions(gbi.CustomFieldDefinitions, desired)
+	if len(changes) > 0 {
+		gbi.CustomFieldDefinitions = ApplyFieldChanges(gbi.CustomFieldDefinitions, changes)
+	}
+	return changes
+}
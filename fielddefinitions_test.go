@@ -0,0 +1,165 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func fieldNames(defs []sweap.CustomFieldDefinitions) []string {
+	names := []string{}
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestCustomFieldDefinitionOperations(t *testing.T) {
+	defs := []sweap.CustomFieldDefinitions{
+		{ID: "a", Name: "Title", Type: "TEXT", SortIndex: 1},
+		{ID: "b", Name: "Company", Type: "TEXT", SortIndex: 2},
+	}
+
+	added, err := sweap.AddCustomFieldDefinition(defs, sweap.CustomFieldDefinitions{Name: "Diet", Type: "SELECT", Options: []string{"none", "vegan"}})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, added[2].SortIndex)
+	assert.Len(t, defs, 2)
+	_, err = sweap.AddCustomFieldDefinition(defs, sweap.CustomFieldDefinitions{Name: "title"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+
+	renamed, err := sweap.RenameCustomFieldDefinition(added, "b", "Organisation")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Title", "Organisation", "Diet"}, fieldNames(renamed))
+	assert.Equal(t, "Company", added[1].Name)
+	_, err = sweap.RenameCustomFieldDefinition(added, "b", "Title")
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+
+	reordered, err := sweap.ReorderCustomFieldDefinitions(renamed, []string{"b"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Organisation", "Title", "Diet"}, fieldNames(reordered))
+	assert.Equal(t, []int{1, 2, 3}, []int{reordered[0].SortIndex, reordered[1].SortIndex, reordered[2].SortIndex})
+	_, err = sweap.ReorderCustomFieldDefinitions(renamed, []string{"b", "b"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+
+	removed, err := sweap.RemoveCustomFieldDefinition(reordered, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Organisation", "Diet"}, fieldNames(removed))
+	_, err = sweap.RemoveCustomFieldDefinition(removed, "a")
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
+
+func TestDiffCustomFieldDefinitions(t *testing.T) {
+	actual := []sweap.CustomFieldDefinitions{
+		{ID: "a", Name: "Title", Type: "TEXT", SortIndex: 1},
+		{ID: "b", Name: "Diet", Type: "SELECT", SortIndex: 2, Options: []interface{}{"none", "vegan"}},
+		{ID: "c", Name: "Shoe size", Type: "NUMBER", SortIndex: 3},
+	}
+	desired := []sweap.CustomFieldDefinitions{
+		{Name: "title", Type: "TEXT", SortIndex: 1},
+		{ID: "b", Name: "Diet", Type: "SELECT", Options: []string{"none", "vegan", "vegetarian"}, GroupName: "Catering"},
+		{Name: "Company", Type: "TEXT"},
+	}
+
+	changes := sweap.DiffCustomFieldDefinitions(actual, desired)
+	assert.Len(t, changes, 4)
+	assert.Equal(t, sweap.FIELDUPDATED, changes[0].Kind) // the case of the name differs
+	assert.Equal(t, "a", changes[0].New.ID)
+	assert.Equal(t, sweap.FIELDUPDATED, changes[1].Kind)
+	assert.Equal(t, 2, changes[1].New.SortIndex)
+	assert.Equal(t, sweap.FIELDADDED, changes[2].Kind)
+	assert.Equal(t, 4, changes[2].New.SortIndex)
+	assert.Equal(t, sweap.FIELDREMOVED, changes[3].Kind)
+	assert.Equal(t, "remove Shoe size (c)", changes[3].String())
+
+	applied := sweap.ApplyFieldChanges(actual, changes)
+	assert.Equal(t, []string{"title", "Diet", "Company"}, fieldNames(applied))
+	assert.Empty(t, sweap.DiffCustomFieldDefinitions(applied, desired))
+	assert.Empty(t, sweap.DiffCustomFieldDefinitions(actual, actual))
+
+	// options decoded from JSON equal the same options given as strings
+	same := []sweap.CustomFieldDefinitions{actual[0], actual[1], actual[2]}
+	same[1].Options = []string{"none", "vegan"}
+	assert.Empty(t, sweap.DiffCustomFieldDefinitions(actual, same))
+}
+
+func TestSyncCustomFieldDefinitionsWithoutIDs(t *testing.T) {
+	// definitions of a guest bulk import have no IDs before the import is created
+	gbi := sweap.GuestBulkImport{CustomFieldDefinitions: []sweap.CustomFieldDefinitions{
+		{Name: "A", Type: "TEXT"},
+		{Name: "B", Type: "TEXT"},
+	}}
+	changes := gbi.SyncCustomFieldDefinitions([]sweap.CustomFieldDefinitions{{Name: "B", Type: "NUMBER"}})
+	assert.Len(t, changes, 2)
+	assert.Equal(t, sweap.FIELDUPDATED, changes[0].Kind)
+	assert.Equal(t, sweap.FIELDREMOVED, changes[1].Kind)
+	assert.Equal(t, []sweap.CustomFieldDefinitions{{Name: "B", Type: "NUMBER"}}, gbi.CustomFieldDefinitions)
+
+	// the renamed definition is not taken for the removed one of the same name
+	gbi.CustomFieldDefinitions = []sweap.CustomFieldDefinitions{{Name: "A", Type: "TEXT"}, {Name: "B", Type: "TEXT", SortIndex: 1}}
+	applied := sweap.ApplyFieldChanges(gbi.CustomFieldDefinitions, []sweap.FieldChange{
+		{Kind: sweap.FIELDUPDATED, Old: gbi.CustomFieldDefinitions[1], New: sweap.CustomFieldDefinitions{Name: "A", Type: "NUMBER", SortIndex: 1}},
+		{Kind: sweap.FIELDREMOVED, Old: gbi.CustomFieldDefinitions[0]},
+	})
+	assert.Equal(t, []sweap.CustomFieldDefinitions{{Name: "A", Type: "NUMBER", SortIndex: 1}}, applied)
+}
+
+func TestSyncCustomFieldDefinitions(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{
+		ID: "event-1", Name: "Fields", State: string(sweap.ACTIVE),
+		CustomFieldDefinitions: []sweap.CustomFieldDefinitions{
+			{ID: "a", Name: "Title", Type: "TEXT", SortIndex: 1},
+			{ID: "b", Name: "Obsolete", Type: "TEXT", SortIndex: 2},
+		},
+	}}})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	desired := []sweap.CustomFieldDefinitions{
+		{Name: "Title", Type: "TEXT"},
+		{Name: "Company", Type: "TEXT"},
+	}
+	changes, err := api.SyncCustomFieldDefinitions("event-1", desired)
+	assert.Nil(t, err)
+	assert.Len(t, changes, 2)
+
+	e, _ := srv.Event("event-1")
+	assert.Equal(t, []string{"Title", "Company"}, fieldNames(e.CustomFieldDefinitions))
+	assert.Equal(t, "a", e.CustomFieldDefinitions[0].ID)
+	assert.NotEmpty(t, e.CustomFieldDefinitions[1].ID)
+	assert.Equal(t, string(sweap.ACTIVE), e.State)
+
+	puts := len(srv.Requests())
+	changes, err = api.SyncCustomFieldDefinitions("event-1", desired)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+	assert.Len(t, srv.Requests(), puts+1) // only the GET
+
+	gbi := sweap.GuestBulkImport{CustomFieldDefinitions: e.CustomFieldDefinitions}
+	changes = gbi.SyncCustomFieldDefinitions(desired[:1])
+	assert.Len(t, changes, 1)
+	assert.Equal(t, []string{"Title"}, fieldNames(gbi.CustomFieldDefinitions))
+}
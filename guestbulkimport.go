@@ -63,7 +63,7 @@ type GuestBulkImport struct {
 // GetAllBulkImports lists all bulk import objects started or completed.
 // GET /guest-bulk-imports?[PARAMS]
 func (api *Client) GetAllBulkImports(s ...GuestBulkImportSearchParameter) (*GuestBulkImports, error) {
-	if len(s) > 1 {
+	if len(s) > 0 {
 		return api.GetAllBulkImportsContext(context.Background(), s[0])
 	}
 	return api.GetAllBulkImportsContext(context.Background(), NewGuestBulkImportSearchParameter())
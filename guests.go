@@ -98,15 +98,17 @@ const (
 	GONE           AttendanceState = "GONE"
 )
 
-// GuestUpdate carries all update events for guest, let it be new guests, or updated guests
+// GuestUpdate carries all update events for guest, let it be new guests, updated or deleted guests.
+// Value holds the Guest, for deleted guests the last known state.
 type GuestUpdate struct {
 	EventType string
 	Value     interface{}
 }
 
 const (
-	NEWGUEST    = "NEW_GUEST"
-	UPDATEGUEST = "UPDATE_GUEST"
+	NEWGUEST     = "NEW_GUEST"
+	UPDATEGUEST  = "UPDATE_GUEST"
+	DELETEDGUEST = "DELETED_GUEST"
 )
 
 type CustomFields map[string]string
@@ -123,6 +125,9 @@ func (api *Client) CreateGuestContext(ctx context.Context, g Guest) (*Guest, err
 	if g.EventID == "" {
 		return nil, fmt.Errorf("no eventId provided in Guest %v", g)
 	}
+	if err := api.validateGuestFields(ctx, g); err != nil {
+		return nil, err
+	}
 
 	request, _ := json.Marshal(g)
 
@@ -234,9 +239,16 @@ func (api *Client) UpdateGuest(guest Guest) (*Guest, error) {
 // It takes a context.Context object and a Guest object as input.
 // If successful, it returns a pointer to the updated Guest and nil error.
 // If the Guest is missing the EventID or ID, it returns a SweapLibraryError with the appropriate error message.
+// With OptionOptimisticLocking it returns a *ConflictError if the Version of the guest has changed on the server.
 // If an error occurs during the update, it returns nil and the specific error encountered.
 // The function can be called on a Client object.
 func (api *Client) UpdateGuestContext(ctx context.Context, g Guest) (*Guest, error) {
+	return api.updateGuest(ctx, g, api.checkVersion)
+}
+
+// updateGuest updates the guest, checking its Version first if checkVersion is set.
+// The fields named in explicit (by their JSON name) are sent even if they are empty.
+func (api *Client) updateGuest(ctx context.Context, g Guest, checkVersion bool, explicit ...string) (*Guest, error) {
 	// Check if the EventID is missing in the Guest object
 	if g.EventID == "" {
 		return nil, SweapLibraryError{Message: fmt.Sprintf("no event ID given in Guest %v", g)}
@@ -247,8 +259,22 @@ func (api *Client) UpdateGuestContext(ctx context.Context, g Guest) (*Guest, err
 		return nil, SweapLibraryError{Message: "no guest ID given"}
 	}
 
+	if err := api.validateGuestFields(ctx, g); err != nil {
+		return nil, err
+	}
+
+	if checkVersion {
+		current, err := api.GetGuestByIdContext(ctx, g.ID)
+		if err != nil {
+			return nil, err
+		}
+		if current.Version != g.Version {
+			return nil, &ConflictError{Resource: "guest", ID: g.ID, Version: g.Version, CurrentVersion: current.Version}
+		}
+	}
+
 	// Marshal the Guest object into JSON
-	request, err := json.Marshal(g)
+	request, err := guestPayload(g, explicit)
 	if err != nil {
 		return nil, err
 	}
@@ -378,3 +404,16 @@ func (api *Client) GetGuestsPaginatedContext(ctx context.Context, eventId string
 
 	return response, nil
 }
+
+// IterateGuests returns an iterator over all guests of an event matching the search parameters.
+// Pages of guests/paginated are fetched lazily while iterating.
+func (api *Client) IterateGuests(ctx context.Context, eventId string, params GuestSearchParameter, options ...IteratorOptions) *PageIterator[Guest] {
+	fetch := func(ctx context.Context, page, size int) ([]Guest, int, error) {
+		p, err := api.GetGuestsPaginatedContext(ctx, eventId, PaginationParameter{Page: page, Size: size}, params)
+		if err != nil {
+			return nil, 0, err
+		}
+		return p.Content, p.Pageable.TotalPages, nil
+	}
+	return NewPageIterator[Guest](ctx, fetch, options...)
+}
@@ -0,0 +1,316 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ImportOptions control ImportGuests. Zero values select the defaults.
+type ImportOptions struct {
+	Name                   string                   // name of the guest bulk import
+	ExternalID             string                   // externalId of the guest bulk import
+	CustomFieldDefinitions []CustomFieldDefinitions // custom field definitions of the guest bulk import
+	BatchSize              int                      // guests per uploaded batch, default 500
+	Parallelism            int                      // batches uploaded concurrently, default 4
+	PollInterval           time.Duration            // first delay between state polls, doubled on every poll, default 1s
+	MaxPollInterval        time.Duration            // upper bound for the delay between state polls, default 30s
+	Timeout                time.Duration            // how long to wait for the import once the upload is finished, default 30m
+	Progress               func(ImportProgress)     // called whenever a batch is uploaded or the state changes
+	JournalDir             string                   // directory journaling uploaded batches to resume interrupted imports, requires ExternalID
+}
+
+func (o ImportOptions) withDefaults() ImportOptions {
+	if o.BatchSize <= 0 {
+		o.BatchSize = 500
+	}
+	if o.Parallelism <= 0 {
+		o.Parallelism = 4
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = time.Second
+	}
+	if o.MaxPollInterval <= 0 {
+		o.MaxPollInterval = 30 * time.Second
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 30 * time.Minute
+	}
+	return o
+}
+
+// ImportProgress is reported to ImportOptions.Progress.
+type ImportProgress struct {
+	BulkImportID    string
+	State           GuestBulkImportStatus
+	BatchesUploaded int
+	Batches         int
+	GuestsUploaded  int
+	Guests          int
+}
+
+// ImportResult describes a bulk import run by ImportGuests.
+type ImportResult struct {
+	BulkImport     GuestBulkImport
+	Batches        int
+	Guests         int
+	Duration       time.Duration
+	Resumed        bool // an interrupted import has been continued
+	BatchesSkipped int  // batches uploaded by an earlier run
+}
+
+// ImportGuests imports guests into an event with a guest bulk import. The guests are uploaded in
+// batches, several at a time, then the upload is finished and the state of the import is polled
+// until it is IMPORT_FINISHED. If the import fails after the bulk import has been created, the
+// returned result carries its ID together with the error.
+//
+// With a JournalDir every uploaded batch is journaled with a hash of its content, and the ExternalID
+// identifies the import. If an import with the same ExternalID has not finished, e.g. because the
+// process died, ImportGuests resumes it: batches in the journal are skipped, provided they are
+// unchanged. Batches uploaded but not yet journaled when the process died are found among the
+// guests the server holds for the import and skipped as well; if the guests on the server do not
+// match the batches, ImportGuests fails instead of uploading duplicates. An import with the same
+// ExternalID whose upload has finished is waited for. If it has already finished, it is returned
+// only if its journal shows that the same guests have been uploaded, e.g. because the process died
+// before the journal was removed; otherwise ImportGuests fails and a new ExternalID is needed.
+func (api *Client) ImportGuests(ctx context.Context, eventId string, guests Guests, opts ImportOptions) (*ImportResult, error) {
+	if eventId == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+	if len(guests) == 0 {
+		return nil, SweapLibraryError{"no guests given"}
+	}
+	if opts.JournalDir != "" && opts.ExternalID == "" {
+		return nil, SweapLibraryError{"resumable imports need an ExternalID"}
+	}
+	opts = opts.withDefaults()
+	start := time.Now()
+
+	batches := chunkGuests(guests, opts.BatchSize)
+	hashes, err := batchHashes(batches)
+	if err != nil {
+		return nil, err
+	}
+
+	gbi, resumed, err := api.findOrCreateBulkImport(ctx, eventId, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImportResult{BulkImport: *gbi, Batches: len(batches), Guests: len(guests), Resumed: resumed}
+	state := UPLOADSTARTED
+	if gbi.State != nil {
+		state = *gbi.State
+	}
+
+	var journal *importJournal
+	switch {
+	case opts.JournalDir == "":
+	case resumed && state == IMPORTFINISHED:
+		if journal, err = finishedJournal(opts.JournalDir, gbi.ID, opts.ExternalID, batches, hashes); err != nil {
+			return result, err
+		}
+	default:
+		if journal, err = openJournal(opts.JournalDir, gbi.ID, !resumed || state != UPLOADSTARTED); err != nil {
+			return result, err
+		}
+	}
+	done := map[int]bool{}
+	if state == UPLOADSTARTED {
+		if done, err = journal.check(batches, hashes); err != nil {
+			return result, err
+		}
+		if resumed {
+			var uploaded Guests
+			if gbi.Guests != nil {
+				uploaded = *gbi.Guests
+			}
+			if err := journal.reconcile(uploaded, batches, hashes, done); err != nil {
+				return result, err
+			}
+		}
+		result.BatchesSkipped = len(done)
+	} else {
+		result.BatchesSkipped = len(batches)
+	}
+
+	progress := &importProgress{report: opts.Progress, p: ImportProgress{
+		BulkImportID: gbi.ID, Batches: len(batches), Guests: len(guests),
+	}}
+	progress.skipped(batches, done, state != UPLOADSTARTED)
+	progress.state(state)
+
+	if state == UPLOADSTARTED {
+		if err := api.uploadBatches(ctx, gbi.ID, batches, hashes, done, opts.Parallelism, progress, journal); err != nil {
+			return result, err
+		}
+		if err := api.BulkImportStateContext(ctx, gbi.ID); err != nil {
+			return result, err
+		}
+		progress.state(UPLOADFINISHED)
+	}
+
+	if state != IMPORTFINISHED {
+		if err := api.waitForImport(ctx, gbi.ID, opts, progress); err != nil {
+			return result, err
+		}
+	}
+	if final, err := api.GetSpecificBulkImportContext(ctx, gbi.ID); err == nil {
+		result.BulkImport = *final
+	}
+	result.Duration = time.Since(start)
+	return result, journal.remove()
+}
+
+func chunkGuests(guests Guests, size int) []Guests {
+	var batches []Guests
+	for len(guests) > size {
+		batches = append(batches, guests[:size])
+		guests = guests[size:]
+	}
+	return append(batches, guests)
+}
+
+// uploadBatches uploads the batches that are not done with at most parallelism concurrent requests
+// and journals them. The first failing upload stops the remaining ones.
+func (api *Client) uploadBatches(ctx context.Context, gbiId string, batches []Guests, hashes []string, done map[int]bool, parallelism int, progress *importProgress, journal *importJournal) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	work := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for w := 0; w < parallelism && w < len(batches); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				err := api.BulkImportUpdateBatchContext(ctx, gbiId, batches[i])
+				if err == nil {
+					err = journal.record(i, hashes[i], len(batches[i]))
+				}
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("uploading batch %d of %d: %w", i+1, len(batches), err)
+						cancel()
+					})
+					continue
+				}
+				progress.uploaded(len(batches[i]))
+			}
+		}()
+	}
+
+feed:
+	for i := range batches {
+		if done[i] {
+			continue
+		}
+		select {
+		case work <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// waitForImport polls the state of the bulk import with increasing delays until it is finished.
+func (api *Client) waitForImport(ctx context.Context, gbiId string, opts ImportOptions, progress *importProgress) error {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	backoff := RetryPolicy{BaseDelay: opts.PollInterval, MaxDelay: opts.MaxPollInterval, Jitter: 0.2}
+	for poll := 1; ; poll++ {
+		if err := sleepContext(ctx, backoff.backoff(poll)); err != nil {
+			return api.importTimeout(ctx, gbiId, opts, err)
+		}
+		s, err := api.GetSpecificBulkImportStateContext(ctx, gbiId)
+		if err != nil {
+			return api.importTimeout(ctx, gbiId, opts, err)
+		}
+		progress.state(s.State)
+		if s.State == IMPORTFINISHED {
+			return nil
+		}
+	}
+}
+
+func (api *Client) importTimeout(ctx context.Context, gbiId string, opts ImportOptions, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return SweapLibraryError{fmt.Sprintf("guest bulk import %v did not finish within %v", gbiId, opts.Timeout)}
+	}
+	return err
+}
+
+// importProgress serializes the progress reports of concurrent uploads.
+type importProgress struct {
+	mu     sync.Mutex
+	report func(ImportProgress)
+	p      ImportProgress
+}
+
+func (ip *importProgress) uploaded(guests int) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	ip.p.BatchesUploaded++
+	ip.p.GuestsUploaded += guests
+	ip.emit()
+}
+
+// skipped counts the batches uploaded by an earlier run, all if the upload is finished.
+func (ip *importProgress) skipped(batches []Guests, done map[int]bool, all bool) {
+	for i, b := range batches {
+		if all || done[i] {
+			ip.p.BatchesUploaded++
+			ip.p.GuestsUploaded += len(b)
+		}
+	}
+}
+
+func (ip *importProgress) state(s GuestBulkImportStatus) {
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	if s == ip.p.State {
+		return
+	}
+	ip.p.State = s
+	ip.emit()
+}
+
+func (ip *importProgress) emit() {
+	if ip.report != nil {
+		ip.report(ip.p)
+	}
+}
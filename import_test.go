@@ -0,0 +1,372 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func importGuests(n int) sweap.Guests {
+	guests := make(sweap.Guests, n)
+	for i := range guests {
+		guests[i] = sweap.Guest{EventID: "event-1", FirstName: fmt.Sprintf("Guest %d", i)}
+	}
+	return guests
+}
+
+func TestImportGuests(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	srv.ImportPolls = 3
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	var mu sync.Mutex
+	var reports []sweap.ImportProgress
+	result, err := api.ImportGuests(context.Background(), "event-1", importGuests(23), sweap.ImportOptions{
+		Name:         "Nightly",
+		ExternalID:   "crm-import-1",
+		BatchSize:    5,
+		Parallelism:  3,
+		PollInterval: time.Millisecond,
+		Progress: func(p sweap.ImportProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			reports = append(reports, p)
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, result.Batches)
+	assert.Equal(t, 23, result.Guests)
+	assert.Equal(t, sweap.IMPORTFINISHED, *result.BulkImport.State)
+	assert.Equal(t, "crm-import-1", result.BulkImport.ExternalID)
+	assert.Len(t, srv.Guests("event-1"), 23)
+
+	states := []sweap.GuestBulkImportStatus{}
+	for _, r := range reports {
+		if len(states) == 0 || states[len(states)-1] != r.State {
+			states = append(states, r.State)
+		}
+	}
+	assert.Equal(t, []sweap.GuestBulkImportStatus{sweap.UPLOADSTARTED, sweap.UPLOADFINISHED, sweap.IMPORTSTARTED, sweap.IMPORTFINISHED}, states)
+	last := reports[len(reports)-1]
+	assert.Equal(t, 5, last.BatchesUploaded)
+	assert.Equal(t, 23, last.GuestsUploaded)
+}
+
+func TestImportGuestsFailingBatch(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	defer srv.Close()
+
+	uploads := 0
+	var mu sync.Mutex
+	failing := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && strings.HasSuffix(req.URL.Path, "/upload-batch") {
+			mu.Lock()
+			uploads++
+			n := uploads
+			mu.Unlock()
+			if n == 2 {
+				return nil, errors.New("connection reset")
+			}
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	api, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: failing}))
+
+	result, err := api.ImportGuests(context.Background(), "event-1", importGuests(10), sweap.ImportOptions{BatchSize: 2, Parallelism: 1})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "uploading batch 2 of 5")
+	assert.NotEmpty(t, result.BulkImport.ID)
+	gbi, _ := srv.BulkImport(result.BulkImport.ID)
+	assert.Equal(t, sweap.UPLOADSTARTED, *gbi.State)
+	assert.Empty(t, srv.Guests("event-1"))
+}
+
+func TestImportGuestsTimeout(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	srv.ImportPolls = 1000
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	_, err := api.ImportGuests(context.Background(), "event-1", importGuests(3), sweap.ImportOptions{
+		PollInterval: time.Millisecond, MaxPollInterval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond,
+	})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Contains(t, err.Error(), "did not finish within 50ms")
+
+	_, err = api.ImportGuests(context.Background(), "event-1", nil, sweap.ImportOptions{})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// failingUploads lets the upload of the given batch fail, counting from 1.
+func failingUploads(batch int) http.RoundTripper {
+	var mu sync.Mutex
+	uploads := 0
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodPut && strings.HasSuffix(req.URL.Path, "/upload-batch") {
+			mu.Lock()
+			uploads++
+			n := uploads
+			mu.Unlock()
+			if n == batch {
+				return nil, errors.New("connection reset")
+			}
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+}
+
+func TestImportGuestsResume(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	defer srv.Close()
+	dir := t.TempDir()
+	opts := sweap.ImportOptions{ExternalID: "nightly-1", JournalDir: dir, BatchSize: 4, Parallelism: 1, PollInterval: time.Millisecond}
+	guests := importGuests(10)
+
+	crashing, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: failingUploads(3)}))
+	first, err := crashing.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.NotNil(t, err)
+	_, err = os.Stat(sweap.JournalFile(dir, first.BulkImport.ID))
+	assert.Nil(t, err)
+
+	var uploaded []int
+	api, _ := srv.Client()
+	opts.Progress = func(p sweap.ImportProgress) { uploaded = append(uploaded, p.BatchesUploaded) }
+	result, err := api.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.Nil(t, err)
+	assert.True(t, result.Resumed)
+	assert.Equal(t, 2, result.BatchesSkipped)
+	assert.Equal(t, first.BulkImport.ID, result.BulkImport.ID)
+	assert.Equal(t, 2, uploaded[0])
+	assert.Equal(t, 3, uploaded[len(uploaded)-1])
+	assert.Len(t, srv.Guests("event-1"), 10)
+
+	_, err = os.Stat(sweap.JournalFile(dir, result.BulkImport.ID))
+	assert.True(t, os.IsNotExist(err))
+
+	// the import is finished and its journal removed, nothing shows which guests it holds
+	_, err = api.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Contains(t, err.Error(), "new external ID")
+	assert.Len(t, srv.Guests("event-1"), 10)
+
+	// a new external ID starts a new import
+	opts.ExternalID = "nightly-1b"
+	result, err = api.ImportGuests(context.Background(), "event-1", guests[:1], opts)
+	assert.Nil(t, err)
+	assert.False(t, result.Resumed)
+	assert.Len(t, srv.Guests("event-1"), 11)
+}
+
+// lostStatePolls lets the polls of the import state reach the server, but fails them for the client.
+func lostStatePolls() http.RoundTripper {
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err == nil && strings.HasSuffix(req.URL.Path, "/state") && req.Method == http.MethodGet {
+			resp.Body.Close()
+			return nil, errors.New("connection reset")
+		}
+		return resp, err
+	})
+}
+
+func TestImportGuestsResumeFinished(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	defer srv.Close()
+	srv.ImportPolls = 0 // the first poll finishes the import
+	dir := t.TempDir()
+	opts := sweap.ImportOptions{ExternalID: "nightly-5", JournalDir: dir, BatchSize: 4, Parallelism: 1, PollInterval: time.Millisecond, Timeout: 50 * time.Millisecond}
+	guests := importGuests(10)
+
+	// the import finishes, but the client dies before it learns about it
+	crashing, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: lostStatePolls()}))
+	first, err := crashing.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.NotNil(t, err)
+	gbi, _ := srv.BulkImport(first.BulkImport.ID)
+	assert.Equal(t, sweap.IMPORTFINISHED, *gbi.State)
+	assert.Len(t, srv.Guests("event-1"), 10)
+
+	// other guests with the same external ID are not taken as imported
+	api, _ := srv.Client()
+	changed := importGuests(10)
+	changed[9].LastName = "Changed"
+	_, err = api.ImportGuests(context.Background(), "event-1", changed, opts)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	_, err = api.ImportGuests(context.Background(), "event-1", guests[:5], opts)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+
+	// the journal shows that the same guests have been imported
+	result, err := api.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.Nil(t, err)
+	assert.True(t, result.Resumed)
+	assert.Equal(t, first.BulkImport.ID, result.BulkImport.ID)
+	assert.Equal(t, 3, result.BatchesSkipped)
+	assert.Len(t, srv.Guests("event-1"), 10)
+	_, err = os.Stat(sweap.JournalFile(dir, first.BulkImport.ID))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// lostResponses lets the given upload reach the server, but fails it for the client, counting from 1.
+func lostResponses(batch int) http.RoundTripper {
+	var mu sync.Mutex
+	uploads := 0
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if req.Method == http.MethodPut && strings.HasSuffix(req.URL.Path, "/upload-batch") {
+			mu.Lock()
+			uploads++
+			n := uploads
+			mu.Unlock()
+			if n == batch && err == nil {
+				resp.Body.Close()
+				return nil, errors.New("connection reset")
+			}
+		}
+		return resp, err
+	})
+}
+
+func TestImportGuestsResumeUnjournaledBatch(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	defer srv.Close()
+	dir := t.TempDir()
+	opts := sweap.ImportOptions{ExternalID: "nightly-4", JournalDir: dir, BatchSize: 4, Parallelism: 1, PollInterval: time.Millisecond}
+	guests := importGuests(10)
+
+	// batch 2 is uploaded, but not journaled
+	crashing, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: lostResponses(2)}))
+	first, err := crashing.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.NotNil(t, err)
+	gbi, _ := srv.BulkImport(first.BulkImport.ID)
+	assert.Len(t, *gbi.Guests, 8)
+
+	api, _ := srv.Client()
+	result, err := api.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.BatchesSkipped)
+	assert.Len(t, srv.Guests("event-1"), 10)
+}
+
+func TestImportGuestsResumeUnknownGuests(t *testing.T) {
+	state := sweap.UPLOADSTARTED
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Import"}},
+		BulkImports: sweap.GuestBulkImports{{ID: "gbi-1", EventId: "event-1", ExternalID: "nightly-5", State: &state,
+			Guests: &sweap.Guests{{FirstName: "Someone else"}}}},
+	})
+	defer srv.Close()
+	dir := t.TempDir()
+	assert.Nil(t, os.WriteFile(sweap.JournalFile(dir, "gbi-1"), nil, 0o644))
+	api, _ := srv.Client()
+
+	_, err := api.ImportGuests(context.Background(), "event-1", importGuests(3), sweap.ImportOptions{
+		ExternalID: "nightly-5", JournalDir: dir, PollInterval: time.Millisecond,
+	})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Contains(t, err.Error(), "without duplicates")
+	for _, r := range srv.Requests() {
+		assert.NotContains(t, r.Path, "upload-batch")
+	}
+}
+
+func TestImportGuestsResumeChecks(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Import"}}})
+	defer srv.Close()
+	dir := t.TempDir()
+	opts := sweap.ImportOptions{ExternalID: "nightly-2", JournalDir: dir, BatchSize: 4, Parallelism: 1, PollInterval: time.Millisecond}
+	guests := importGuests(10)
+
+	crashing, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: failingUploads(2)}))
+	first, err := crashing.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.NotNil(t, err)
+
+	api, _ := srv.Client()
+	changed := append(sweap.Guests{{EventID: "event-1", FirstName: "Someone else"}}, guests[1:]...)
+	_, err = api.ImportGuests(context.Background(), "event-1", changed, opts)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Contains(t, err.Error(), "batch 1 differs")
+
+	assert.Nil(t, os.Remove(sweap.JournalFile(dir, first.BulkImport.ID)))
+	_, err = api.ImportGuests(context.Background(), "event-1", guests, opts)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Contains(t, err.Error(), "no journal")
+
+	_, err = api.ImportGuests(context.Background(), "event-1", guests, sweap.ImportOptions{JournalDir: dir})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
+
+func TestImportGuestsResumeAfterUpload(t *testing.T) {
+	state := sweap.UPLOADFINISHED
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Import"}},
+		BulkImports: sweap.GuestBulkImports{{ID: "gbi-1", EventId: "event-1", ExternalID: "nightly-3", State: &state,
+			Guests: &sweap.Guests{{FirstName: "Uploaded"}}}},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	result, err := api.ImportGuests(context.Background(), "event-1", importGuests(1), sweap.ImportOptions{
+		ExternalID: "nightly-3", JournalDir: t.TempDir(), PollInterval: time.Millisecond,
+	})
+	assert.Nil(t, err)
+	assert.True(t, result.Resumed)
+	assert.Equal(t, "gbi-1", result.BulkImport.ID)
+	guests := srv.Guests("event-1")
+	assert.Len(t, guests, 1)
+	assert.Equal(t, "Uploaded", guests[0].FirstName)
+	for _, r := range srv.Requests() {
+		assert.NotContains(t, r.Path, "upload-batch")
+	}
+}
+
+func TestGetAllBulkImportsFilter(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events:      sweap.Events{{ID: "event-1", Name: "Import"}},
+		BulkImports: sweap.GuestBulkImports{{ID: "gbi-1", EventId: "event-1", ExternalID: "a"}, {ID: "gbi-2", EventId: "event-1", ExternalID: "b"}},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	found, err := api.GetAllBulkImports(sweap.GuestBulkImportSearchParameter{ExternalID: "b"})
+	assert.Nil(t, err)
+	assert.Len(t, *found, 1)
+	assert.Equal(t, "gbi-2", (*found)[0].ID)
+}
@@ -0,0 +1,263 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JournalFile returns the file in dir journaling the uploaded batches of a guest bulk import.
+func JournalFile(dir, gbiId string) string {
+	return filepath.Join(dir, "bulkimport-"+gbiId+".jsonl")
+}
+
+// journalEntry is a line of a journal file, written after a batch has been uploaded.
+type journalEntry struct {
+	Batch      int       `json:"batch"`
+	Hash       string    `json:"hash"`
+	Guests     int       `json:"guests"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+// importJournal records which batches of a guest bulk import have been uploaded,
+// so that an interrupted import can be resumed. A nil journal records nothing.
+type importJournal struct {
+	path    string
+	mu      sync.Mutex
+	batches map[int]string // hash by batch index
+}
+
+// openJournal reads the journal of a guest bulk import. If it does not exist, it is created
+// if create is set. A partly written last line, left by a crash, is ignored.
+func openJournal(dir, gbiId string, create bool) (*importJournal, error) {
+	j := &importJournal{path: JournalFile(dir, gbiId), batches: map[int]string{}}
+
+	f, err := os.Open(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, SweapLibraryError{fmt.Sprintf("no journal %v for the unfinished guest bulk import %v, delete the import to start over", j.path, gbiId)}
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		return j, os.WriteFile(j.path, nil, 0o644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e journalEntry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			j.batches[e.Batch] = e.Hash
+		}
+	}
+	return j, sc.Err()
+}
+
+// check returns the indexes of the batches already uploaded. It fails if the journal
+// does not match the batches, e.g. because the guests or the batch size changed.
+func (j *importJournal) check(batches []Guests, hashes []string) (map[int]bool, error) {
+	done := map[int]bool{}
+	if j == nil {
+		return done, nil
+	}
+	for i, h := range j.batches {
+		if i >= len(batches) || hashes[i] != h {
+			return nil, SweapLibraryError{fmt.Sprintf("batch %d differs from the one uploaded before (%v), cannot resume", i+1, j.path)}
+		}
+		done[i] = true
+	}
+	return done, nil
+}
+
+func (j *importJournal) record(batch int, hash string, guests int) error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	line, err := json.Marshal(journalEntry{Batch: batch, Hash: hash, Guests: guests, UploadedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if err := appendLine(j.path, line); err != nil {
+		return err
+	}
+	j.batches[batch] = hash
+	return nil
+}
+
+func (j *importJournal) remove() error {
+	if j == nil {
+		return nil
+	}
+	return os.Remove(j.path)
+}
+
+func batchHashes(batches []Guests) ([]string, error) {
+	hashes := make([]string, len(batches))
+	for i, b := range batches {
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		hashes[i] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// finishedJournal opens the journal of a finished guest bulk import. It fails
+// unless the journal shows that all batches have been uploaded unchanged, as nothing else proves
+// that the import holds the guests given now.
+func finishedJournal(dir, gbiId, externalID string, batches []Guests, hashes []string) (*importJournal, error) {
+	other := SweapLibraryError{fmt.Sprintf("the guest bulk import %v with external ID %v has already finished and cannot be shown to hold the same guests, use a new external ID", gbiId, externalID)}
+	if _, err := os.Stat(JournalFile(dir, gbiId)); errors.Is(err, os.ErrNotExist) {
+		return nil, other
+	}
+	j, err := openJournal(dir, gbiId, false)
+	if err != nil {
+		return nil, err
+	}
+	if done, err := j.check(batches, hashes); err != nil || len(done) != len(batches) {
+		return nil, other
+	}
+	return j, nil
+}
+
+// reconcile marks the batches that have been uploaded by an interrupted run but not journaled as
+// done, so that they are not uploaded a second time. The guests the server holds for the import
+// are matched against the batches missing in the journal. It fails if the guests on the server
+// cannot be explained by whole batches.
+func (j *importJournal) reconcile(uploaded Guests, batches []Guests, hashes []string, done map[int]bool) error {
+	if j == nil {
+		return nil
+	}
+	journaled := 0
+	for i := range done {
+		journaled += len(batches[i])
+	}
+	if len(uploaded) == journaled {
+		return nil
+	}
+
+	unjournaled := map[string]int{} // uploaded guests not in a journaled batch, by key
+	for _, g := range uploaded {
+		unjournaled[importKey(g)]++
+	}
+	for i := range done {
+		for _, g := range batches[i] {
+			unjournaled[importKey(g)]--
+		}
+	}
+
+	found := journaled
+	for i, b := range batches {
+		if done[i] || !uploadedBatch(unjournaled, b) {
+			continue
+		}
+		for _, g := range b {
+			unjournaled[importKey(g)]--
+		}
+		if err := j.record(i, hashes[i], len(b)); err != nil {
+			return err
+		}
+		done[i] = true
+		found += len(b)
+	}
+	if found != len(uploaded) {
+		return SweapLibraryError{fmt.Sprintf("the guest bulk import holds %d guests, but the batches in %v account for %d, cannot resume without duplicates", len(uploaded), j.path, found)}
+	}
+	return nil
+}
+
+// uploadedBatch reports whether all guests of the batch are among the uploaded guests.
+func uploadedBatch(uploaded map[string]int, batch Guests) bool {
+	needed := map[string]int{}
+	for _, g := range batch {
+		k := importKey(g)
+		needed[k]++
+		if needed[k] > uploaded[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// importKey identifies an uploaded guest, as the server does not return the uploaded guests verbatim.
+func importKey(g Guest) string {
+	return strings.Join([]string{externalIDString(g.ExternalID), strings.ToLower(g.Email), g.FirstName, g.LastName}, "\x00")
+}
+
+// findOrCreateBulkImport returns the guest bulk import of the event with the external ID of opts if
+// there is one, otherwise it creates a new one. An unfinished import is preferred over a finished one,
+// which ImportGuests only accepts if its journal matches the guests.
+func (api *Client) findOrCreateBulkImport(ctx context.Context, eventId string, opts ImportOptions) (*GuestBulkImport, bool, error) {
+	if opts.JournalDir != "" {
+		found, err := api.GetAllBulkImportsContext(ctx, GuestBulkImportSearchParameter{EventId: eventId, ExternalID: opts.ExternalID})
+		if err != nil {
+			return nil, false, err
+		}
+		var open, finished GuestBulkImports
+		for _, gbi := range *found {
+			if gbi.State != nil && *gbi.State == IMPORTFINISHED {
+				finished = append(finished, gbi)
+			} else {
+				open = append(open, gbi)
+			}
+		}
+		if len(open) > 1 {
+			return nil, false, SweapLibraryError{fmt.Sprintf("%d unfinished guest bulk imports with external ID %v", len(open), opts.ExternalID)}
+		}
+		if len(open) == 1 {
+			// the list does not necessarily carry the uploaded guests
+			gbi, err := api.GetSpecificBulkImportContext(ctx, open[0].ID)
+			return gbi, true, err
+		}
+		if len(finished) > 0 {
+			return &finished[len(finished)-1], true, nil
+		}
+	}
+
+	gbi, err := api.CreateGuestBulkImportObjectContext(ctx, GuestBulkImport{
+		Name:                   opts.Name,
+		ExternalID:             opts.ExternalID,
+		EventId:                eventId,
+		CustomFieldDefinitions: opts.CustomFieldDefinitions,
+	})
+	return gbi, false, err
+}
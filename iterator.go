@@ -0,0 +1,168 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+)
+
+// PageFetcher fetches the page with the given number and size of a paginated endpoint.
+// It returns the items of the page and the total number of pages.
+type PageFetcher[T any] func(ctx context.Context, page, size int) (items []T, totalPages int, err error)
+
+type iteratorConfig struct {
+	pageSize int
+	prefetch bool
+}
+
+type IteratorOptions func(*iteratorConfig)
+
+// IteratorPageSize sets the number of items fetched with each request. Defaults to 100.
+func IteratorPageSize(size int) func(*iteratorConfig) {
+	return func(c *iteratorConfig) {
+		c.pageSize = size
+	}
+}
+
+// IteratorPrefetch enables fetching the next page in the background while the current page is consumed.
+func IteratorPrefetch(b bool) func(*iteratorConfig) {
+	return func(c *iteratorConfig) {
+		c.prefetch = b
+	}
+}
+
+type pageResult[T any] struct {
+	items      []T
+	totalPages int
+	err        error
+}
+
+// PageIterator lazily walks through all items of a paginated endpoint, fetching pages as needed.
+//
+//	it := api.IterateGuests(ctx, eventId, NewGuestSearchParameters())
+//	for it.Next() {
+//		g := it.Value()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type PageIterator[T any] struct {
+	ctx   context.Context
+	fetch PageFetcher[T]
+	cfg   iteratorConfig
+
+	items      []T
+	index      int
+	current    T
+	page       int // next page to fetch
+	totalPages int // -1 until the first page is fetched
+	pending    chan pageResult[T]
+	err        error
+}
+
+// NewPageIterator creates an iterator over all items returned by fetch.
+// The iterator stops with ctx.Err() once ctx is done.
+func NewPageIterator[T any](ctx context.Context, fetch PageFetcher[T], options ...IteratorOptions) *PageIterator[T] {
+	cfg := iteratorConfig{pageSize: 100}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+	if cfg.pageSize <= 0 {
+		cfg.pageSize = 100
+	}
+
+	return &PageIterator[T]{ctx: ctx, fetch: fetch, cfg: cfg, totalPages: -1}
+}
+
+// Next advances to the next item, which is then available through Value.
+// It returns false when all items have been returned or an error occurred.
+func (it *PageIterator[T]) Next() bool {
+	for it.err == nil {
+		if it.index < len(it.items) {
+			it.current = it.items[it.index]
+			it.index++
+			return true
+		}
+
+		if it.totalPages >= 0 && it.page >= it.totalPages {
+			return false
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = err
+			return false
+		}
+
+		res := it.load()
+		if res.err != nil {
+			it.err = res.err
+			return false
+		}
+		it.items, it.index, it.totalPages = res.items, 0, res.totalPages
+		it.page++
+
+		if it.cfg.prefetch && it.page < it.totalPages {
+			it.prefetch()
+		}
+	}
+	return false
+}
+
+// Value returns the current item.
+func (it *PageIterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *PageIterator[T]) Err() error {
+	return it.err
+}
+
+// Page returns the number of the page the current item belongs to.
+func (it *PageIterator[T]) Page() int {
+	return it.page - 1
+}
+
+// load returns the next page, either from a pending prefetch or by fetching it.
+func (it *PageIterator[T]) load() pageResult[T] {
+	if it.pending == nil {
+		items, totalPages, err := it.fetch(it.ctx, it.page, it.cfg.pageSize)
+		return pageResult[T]{items, totalPages, err}
+	}
+
+	pending := it.pending
+	it.pending = nil
+	select {
+	case res := <-pending:
+		return res
+	case <-it.ctx.Done():
+		return pageResult[T]{err: it.ctx.Err()}
+	}
+}
+
+func (it *PageIterator[T]) prefetch() {
+	// buffered, so that the goroutine never blocks if the iterator is abandoned
+	pending := make(chan pageResult[T], 1)
+	page := it.page
+	go func() {
+		items, totalPages, err := it.fetch(it.ctx, page, it.cfg.pageSize)
+		pending <- pageResult[T]{items, totalPages, err}
+	}()
+	it.pending = pending
+}
@@ -0,0 +1,106 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// numbers returns a PageFetcher over 0..n-1 counting the fetched pages.
+func numbers(n int, fetched *int32) PageFetcher[int] {
+	return func(ctx context.Context, page, size int) ([]int, int, error) {
+		atomic.AddInt32(fetched, 1)
+		items := []int{}
+		for i := page * size; i < (page+1)*size && i < n; i++ {
+			items = append(items, i)
+		}
+		return items, (n + size - 1) / size, nil
+	}
+}
+
+func TestPageIterator(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		var fetched int32
+		it := NewPageIterator(context.Background(), numbers(7, &fetched), IteratorPageSize(3), IteratorPrefetch(prefetch))
+
+		got := []int{}
+		for it.Next() {
+			got = append(got, it.Value())
+		}
+
+		assert.Nil(t, it.Err())
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6}, got)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&fetched))
+	}
+}
+
+func TestPageIteratorIsLazy(t *testing.T) {
+	var fetched int32
+	it := NewPageIterator(context.Background(), numbers(100, &fetched), IteratorPageSize(10))
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(&fetched))
+	assert.True(t, it.Next())
+	assert.Equal(t, 0, it.Value())
+	assert.Equal(t, 0, it.Page())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetched))
+}
+
+func TestPageIteratorEmpty(t *testing.T) {
+	var fetched int32
+	it := NewPageIterator(context.Background(), numbers(0, &fetched))
+
+	assert.False(t, it.Next())
+	assert.Nil(t, it.Err())
+}
+
+func TestPageIteratorError(t *testing.T) {
+	failure := errors.New("failure")
+	fetch := func(ctx context.Context, page, size int) ([]int, int, error) {
+		if page == 1 {
+			return nil, 0, failure
+		}
+		return []int{1}, 3, nil
+	}
+	it := NewPageIterator[int](context.Background(), fetch, IteratorPageSize(1), IteratorPrefetch(true))
+
+	assert.True(t, it.Next())
+	assert.False(t, it.Next())
+	assert.Equal(t, failure, it.Err())
+	assert.False(t, it.Next())
+}
+
+func TestPageIteratorCancel(t *testing.T) {
+	var fetched int32
+	ctx, cancel := context.WithCancel(context.Background())
+	it := NewPageIterator(ctx, numbers(10, &fetched), IteratorPageSize(2))
+
+	assert.True(t, it.Next())
+	assert.True(t, it.Next())
+	cancel()
+	assert.False(t, it.Next())
+	assert.Equal(t, context.Canceled, it.Err())
+}
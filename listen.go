@@ -22,73 +22,298 @@
 package sweap
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"time"
 )
 
 const messageBufferSize int = 256
 
-type ListenOptions func(*Client)
+// Defaults of the incremental polling of Listen.
+const (
+	defaultListenOverlap   = time.Minute
+	defaultListenReconcile = 15 * time.Minute
+)
+
+type listenConfig struct {
+	bufferSize  int
+	emitInitial bool
+	overlap     time.Duration
+	reconcile   time.Duration
+	checkpoints CheckpointStore
+}
+
+type ListenOptions func(*listenConfig)
+
+// ListenBufferSize sets the capacity of the update channel. Defaults to 256.
+func ListenBufferSize(n int) func(*listenConfig) {
+	return func(c *listenConfig) {
+		c.bufferSize = n
+	}
+}
+
+// ListenEmitInitial controls whether the guests found by the first poll are reported as NEW_GUEST. Defaults to true.
+func ListenEmitInitial(b bool) func(*listenConfig) {
+	return func(c *listenConfig) {
+		c.emitInitial = b
+	}
+}
 
-// Listen listens to guest changes (new, updates).
-// It returns a channel of GuestUpdate objects and an error.
-// The channel can be used to receive updates on new or updated guests.
-// If an error occurs during the setup, it returns nil channel and the specific error encountered.
+// ListenOverlap sets how far before the latest seen updatedAt incremental polls start, to tolerate clock skew.
+// Defaults to one minute.
+func ListenOverlap(d time.Duration) func(*listenConfig) {
+	return func(c *listenConfig) {
+		c.overlap = d
+	}
+}
+
+// ListenReconcileEvery sets how often the complete guest list is loaded to detect deleted guests.
+// In between only guests updated since the last poll are loaded. A value <= 0 loads the complete list on every poll.
+// Defaults to 15 minutes.
+func ListenReconcileEvery(d time.Duration) func(*listenConfig) {
+	return func(c *listenConfig) {
+		c.reconcile = d
+	}
+}
+
+// Listen polls the given events every interval and reports guest changes (new, updated, deleted).
+// It returns a channel of GuestUpdate objects and a channel of errors encountered while polling.
+// Only guests updated since the last poll are loaded, see ListenOverlap and ListenReconcileEvery.
+// Deleted guests are therefore detected with the next full reconcile only.
+// Polling continues after errors. Errors are dropped if the error channel is not read, so
+// callers that do not care about errors need not drain it. Both channels are closed once ctx is done.
+// If the arguments are invalid, it returns nil channels and the specific error encountered.
 // The function can be called on a Client object.
-func (api *Client) Listen() (chan *GuestUpdate, error) {
-	messageCh := make(chan *GuestUpdate, messageBufferSize)
+func (api *Client) Listen(ctx context.Context, eventIDs []string, interval time.Duration, options ...ListenOptions) (<-chan *GuestUpdate, <-chan error, error) {
+	if len(eventIDs) == 0 {
+		return nil, nil, errors.New("no event ID given")
+	}
+	if interval <= 0 {
+		return nil, nil, errors.New("poll interval must be positive")
+	}
 
-	search := EventSearchParameter{
-		Name: "Integration Test Event",
+	cfg := listenConfig{
+		bufferSize:  messageBufferSize,
+		emitInitial: true,
+		overlap:     defaultListenOverlap,
+		reconcile:   defaultListenReconcile,
+	}
+	for _, opt := range options {
+		opt(&cfg)
 	}
 
-	events, err := api.SearchEvents(search)
-	if err != nil {
-		return nil, err
+	l := &listener{
+		api:      api,
+		cfg:      cfg,
+		updates:  make(chan *GuestUpdate, cfg.bufferSize),
+		errs:     make(chan error, errorBufferSize),
+		events:   make(map[string]*eventState),
+		interval: interval,
+	}
+	for _, id := range eventIDs {
+		if id == "" {
+			return nil, nil, errors.New("empty event ID given")
+		}
+		l.eventIDs = append(l.eventIDs, id)
+	}
+
+	go l.run(ctx)
+
+	return l.updates, l.errs, nil
+}
+
+// errorBufferSize is the number of errors kept for the caller of Listen before errors are dropped.
+const errorBufferSize = 16
+
+// listener holds the state of a running Listen.
+type listener struct {
+	api      *Client
+	cfg      listenConfig
+	eventIDs []string
+	interval time.Duration
+	updates  chan *GuestUpdate
+	errs     chan error
+	events   map[string]*eventState
+}
+
+// eventState is what a listener knows about the guests of an event.
+type eventState struct {
+	guests        map[string]Guest // last seen state by guest ID
+	highWater     time.Time        // latest updatedAt seen
+	lastReconcile time.Time
+}
+
+func (l *listener) run(ctx context.Context) {
+	defer close(l.errs)
+	defer close(l.updates)
+
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		for _, eventID := range l.eventIDs {
+			if err := l.poll(ctx, eventID); err != nil {
+				if !l.send(ctx, nil, err) {
+					return
+				}
+			}
+		}
+
+		l.api.Debugf("listener going to sleep for %v", l.interval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll loads the guests of an event changed since the last poll and reports the differences.
+func (l *listener) poll(ctx context.Context, eventID string) error {
+	state, initial := l.events[eventID], false
+	if state == nil {
+		var restored bool
+		var err error
+		if state, restored, err = l.restore(eventID); err != nil {
+			return err
+		}
+		initial = !restored
+		l.events[eventID] = state
+	}
+
+	full := state.lastReconcile.IsZero() || state.highWater.IsZero() || time.Since(state.lastReconcile) >= l.cfg.reconcile
+	params := NewGuestSearchParameters()
+	if !full {
+		since := state.highWater.Add(-l.cfg.overlap)
+		params.UpdatedAfter = &since
 	}
 
-	guests, err := api.GetGuests((*events)[0].ID)
+	guests, err := l.api.GetGuestsContext(ctx, eventID, params)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if full {
+		state.lastReconcile = time.Now()
 	}
+	l.api.Debugf("listener loaded %d guests of event %v (full: %v)", len(*guests), eventID, full)
+
+	changed := full
+	defer func() {
+		if changed && ctx.Err() == nil {
+			l.checkpoint(ctx, eventID, state)
+		}
+	}()
 
-	go func() {
-		storedGuests := make([]Guest, 0)
+	seen := make(map[string]bool, len(*guests))
+	for _, g := range *guests {
+		seen[g.ID] = true
+		if g.UpdatedAt != nil && g.UpdatedAt.After(state.highWater) {
+			state.highWater = *g.UpdatedAt
+		}
 
-		for {
-			for _, g := range *guests {
-				if !contains(g, storedGuests) {
-					messageCh <- &GuestUpdate{EventType: NEWGUEST, Value: g}
-				} else {
-					// Handle guest updates here if needed
+		old, ok := state.guests[g.ID]
+		switch {
+		case !ok:
+			changed = true
+			state.guests[g.ID] = g
+			if !initial || l.cfg.emitInitial {
+				if !l.send(ctx, &GuestUpdate{EventType: NEWGUEST, Value: g}, nil) {
+					return ctx.Err()
 				}
 			}
-			storedGuests = *guests
-			fmt.Println("[INFO] Going to sleep")
-			time.Sleep(15 * time.Second)
-			guests, _ = api.GetGuests((*events)[0].ID)
+		case isChanged(g, old):
+			changed = true
+			state.guests[g.ID] = g
+			if !l.send(ctx, &GuestUpdate{EventType: UPDATEGUEST, Value: g}, nil) {
+				return ctx.Err()
+			}
 		}
-	}()
+	}
 
-	return messageCh, nil
+	if !full {
+		return nil
+	}
+	for id, g := range state.guests {
+		if seen[id] {
+			continue
+		}
+		delete(state.guests, id)
+		if !l.send(ctx, &GuestUpdate{EventType: DELETEDGUEST, Value: g}, nil) {
+			return ctx.Err()
+		}
+	}
+	return nil
 }
 
-// contains checks if a guest (g1) is present in a slice of guests (g2).
-// It returns true if the guest is found, and false otherwise.
-func contains(g1 Guest, g2 Guests) bool {
-	for _, g := range g2 {
-		if g1.ID == g.ID {
+// restore returns the state of an event recorded in the checkpoint store, or an empty state.
+// Guests restored from a checkpoint carry ID, EventID, Version and UpdatedAt only.
+func (l *listener) restore(eventID string) (*eventState, bool, error) {
+	state := &eventState{guests: make(map[string]Guest)}
+	if l.cfg.checkpoints == nil {
+		return state, false, nil
+	}
+
+	cp, err := l.cfg.checkpoints.Load(eventID)
+	if err != nil || cp == nil {
+		return state, false, err
+	}
+
+	state.highWater = cp.HighWater
+	for id, v := range cp.Guests {
+		state.guests[id] = Guest{ID: id, EventID: eventID, Version: v.Version, UpdatedAt: v.UpdatedAt}
+	}
+	l.api.Debugf("listener resumed event %v with %d guests from checkpoint", eventID, len(state.guests))
+	return state, true, nil
+}
+
+// checkpoint records the state of an event in the checkpoint store, errors are reported on the error channel.
+func (l *listener) checkpoint(ctx context.Context, eventID string, state *eventState) {
+	if l.cfg.checkpoints == nil {
+		return
+	}
+
+	cp := Checkpoint{EventID: eventID, HighWater: state.highWater, Guests: make(map[string]GuestVersion, len(state.guests))}
+	for id, g := range state.guests {
+		cp.Guests[id] = GuestVersion{Version: g.Version, UpdatedAt: g.UpdatedAt}
+	}
+	if err := l.cfg.checkpoints.Save(cp); err != nil {
+		l.send(ctx, nil, err)
+	}
+}
+
+// send delivers an update or an error. It returns false if ctx is done.
+func (l *listener) send(ctx context.Context, u *GuestUpdate, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if u != nil {
+		select {
+		case l.updates <- u:
 			return true
+		case <-ctx.Done():
+			return false
 		}
 	}
-	return false
+
+	// errors must not stall polling if the caller does not read them
+	select {
+	case l.errs <- err:
+	default:
+		l.api.Debugf("listen: dropped error, error channel is full: %v", err)
+	}
+	return true
+}
+
+// isChanged reports whether guest has been modified compared to the previously seen version.
+func isChanged(guest, previous Guest) bool {
+	return guest.Version > previous.Version || isUpdatedAtAfter(guest, previous)
 }
 
 // isUpdatedAtAfter compares two Guest objects and returns true if the UpdatedAt field of the first guest is after the UpdatedAt field of the second guest.
 // It takes two Guest objects as input.
 // If either of the UpdatedAt fields is nil, it returns false.
 // If the UpdatedAt field of the first guest is after the UpdatedAt field of the second guest, it returns true. Otherwise, it returns false.
-
 func isUpdatedAtAfter(guest1, guest2 Guest) bool {
 	if guest1.UpdatedAt == nil || guest2.UpdatedAt == nil {
 		return false
@@ -0,0 +1,234 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func nextUpdate(t *testing.T, updates <-chan *sweap.GuestUpdate) *sweap.GuestUpdate {
+	t.Helper()
+	select {
+	case u := <-updates:
+		return u
+	case <-time.After(2 * time.Second):
+		t.Fatal("no update received")
+		return nil
+	}
+}
+
+func TestListen(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Listen"}},
+		Guests: sweap.Guests{{ID: "guest-1", EventID: "event-1", FirstName: "Sven"}},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, errs, err := api.Listen(ctx, []string{"event-1"}, 10*time.Millisecond, sweap.ListenReconcileEvery(0))
+	assert.Nil(t, err)
+
+	u := nextUpdate(t, updates)
+	assert.Equal(t, sweap.NEWGUEST, u.EventType)
+	assert.Equal(t, "guest-1", u.Value.(sweap.Guest).ID)
+
+	added := srv.AddGuest(sweap.Guest{EventID: "event-1", FirstName: "Wissam"})
+	u = nextUpdate(t, updates)
+	assert.Equal(t, sweap.NEWGUEST, u.EventType)
+	assert.Equal(t, added.ID, u.Value.(sweap.Guest).ID)
+
+	srv.UpdateGuest("guest-1", func(g *sweap.Guest) { g.LastName = "Frauen" })
+	u = nextUpdate(t, updates)
+	assert.Equal(t, sweap.UPDATEGUEST, u.EventType)
+	assert.Equal(t, "Frauen", u.Value.(sweap.Guest).LastName)
+
+	srv.DeleteGuest(added.ID)
+	u = nextUpdate(t, updates)
+	assert.Equal(t, sweap.DELETEDGUEST, u.EventType)
+	assert.Equal(t, added.ID, u.Value.(sweap.Guest).ID)
+
+	cancel()
+	for range updates {
+	}
+	for range errs {
+	}
+}
+
+func TestListenIncremental(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Listen"}}})
+	defer srv.Close()
+	srv.Now = func() time.Time { return now }
+	srv.AddGuest(sweap.Guest{ID: "guest-1", EventID: "event-1", FirstName: "Sven"})
+	srv.AddGuest(sweap.Guest{ID: "guest-2", EventID: "event-1", FirstName: "Wissam"})
+	api, _ := srv.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, _, err := api.Listen(ctx, []string{"event-1"}, 10*time.Millisecond,
+		sweap.ListenEmitInitial(false), sweap.ListenOverlap(time.Minute), sweap.ListenReconcileEvery(time.Hour))
+	assert.Nil(t, err)
+
+	// wait for the first incremental poll
+	for {
+		reqs := srv.Requests()
+		if len(reqs) > 1 {
+			assert.Equal(t, "", reqs[0].Query.Get("updatedAfter"))
+			assert.Equal(t, "2023-05-01T11:59:00Z", reqs[1].Query.Get("updatedAfter"))
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	now = now.Add(10 * time.Minute)
+	srv.UpdateGuest("guest-2", func(g *sweap.Guest) { g.LastName = "Ghozlan" })
+	srv.DeleteGuest("guest-1")
+
+	u := nextUpdate(t, updates)
+	assert.Equal(t, sweap.UPDATEGUEST, u.EventType)
+	assert.Equal(t, "guest-2", u.Value.(sweap.Guest).ID)
+
+	// the overlap returns guest-2 again, but it is reported once only and the deletion waits for the reconcile
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update %v", u)
+	default:
+	}
+	reqs := srv.Requests()
+	assert.Equal(t, "2023-05-01T12:09:00Z", reqs[len(reqs)-1].Query.Get("updatedAfter"))
+}
+
+func TestListenResumesFromCheckpoint(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Listen"}},
+		Guests: sweap.Guests{
+			{ID: "guest-1", EventID: "event-1", FirstName: "Sven"},
+			{ID: "guest-2", EventID: "event-1", FirstName: "Wissam"},
+			{ID: "guest-3", EventID: "event-1", FirstName: "Matthias"},
+		},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+	store, err := sweap.NewFileCheckpointStore(t.TempDir())
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates, _, err := api.Listen(ctx, []string{"event-1"}, 10*time.Millisecond, sweap.ListenCheckpoints(store))
+	assert.Nil(t, err)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, sweap.NEWGUEST, nextUpdate(t, updates).EventType)
+	}
+	// wait for the checkpoint of the first poll
+	for {
+		if cp, _ := store.Load("event-1"); cp != nil {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	for range updates {
+	}
+
+	// changes while the listener is down
+	srv.UpdateGuest("guest-2", func(g *sweap.Guest) { g.LastName = "Ghozlan" })
+	srv.DeleteGuest("guest-3")
+	added := srv.AddGuest(sweap.Guest{EventID: "event-1", FirstName: "Theo"})
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	updates, _, err = api.Listen(ctx, []string{"event-1"}, 10*time.Millisecond, sweap.ListenCheckpoints(store))
+	assert.Nil(t, err)
+
+	got := map[string]string{}
+	for i := 0; i < 3; i++ {
+		u := nextUpdate(t, updates)
+		got[u.Value.(sweap.Guest).ID] = u.EventType
+	}
+	assert.Equal(t, map[string]string{"guest-2": sweap.UPDATEGUEST, "guest-3": sweap.DELETEDGUEST, added.ID: sweap.NEWGUEST}, got)
+
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update %v", u)
+	default:
+	}
+}
+
+func TestListenErrors(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Listen"}}})
+	api, _ := srv.Client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, errs, err := api.Listen(ctx, []string{"event-1"}, 10*time.Millisecond, sweap.ListenEmitInitial(false))
+	assert.Nil(t, err)
+
+	srv.Close()
+	select {
+	case err := <-errs:
+		assert.NotNil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no error received")
+	}
+}
+
+func TestListenUnreadErrors(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "Listen"}}})
+	defer srv.Close()
+	api, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Query().Get("eventId") == "broken" {
+			return nil, errors.New("connection reset")
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// polling the broken event fails every time, the errors are never read
+	updates, _, err := api.Listen(ctx, []string{"broken", "event-1"}, time.Millisecond, sweap.ListenEmitInitial(false))
+	assert.Nil(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	added := srv.AddGuest(sweap.Guest{EventID: "event-1", FirstName: "Wissam"})
+	u := nextUpdate(t, updates)
+	assert.Equal(t, added.ID, u.Value.(sweap.Guest).ID)
+}
+
+func TestListenInvalidArguments(t *testing.T) {
+	api, _ := sweap.New("", "")
+
+	_, _, err := api.Listen(context.Background(), nil, time.Second)
+	assert.NotNil(t, err)
+	_, _, err = api.Listen(context.Background(), []string{"event-1"}, 0)
+	assert.NotNil(t, err)
+}
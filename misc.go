@@ -40,10 +40,15 @@ type SweapResponse struct {
 	ResponseMetadata ResponseMetadata `json:"response_metadata,omitempty"`
 }
 
+// SweapError is the error reported by the Sweap API in the response body.
+// Use errors.Is with ErrNotFound, ErrDuplicate, ... or errors.As with *ValidationError to inspect it.
 type SweapError struct {
-	Error_  string `json:"error"`
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Error_     string       `json:"error"`
+	Code       int          `json:"code"`
+	Message    string       `json:"message"`
+	Status     Status       `json:"status,omitempty"`
+	Errors     []FieldError `json:"errors,omitempty"`
+	HTTPStatus int          `json:"-"`
 }
 
 func (se *SweapError) Error() string {
@@ -99,13 +104,7 @@ func doPost(ctx context.Context, client httpClient, req *http.Request, parser re
 
 	defer resp.Body.Close()
 
-	err = checkStatusCode(resp, d)
-	if err != nil {
-		sweapError := &SweapError{}
-		e := newJSONParser(sweapError)(resp)
-		if e == nil {
-			return sweapError
-		}
+	if err = responseError(resp, d); err != nil {
 		return err
 	}
 	logResponse(resp, d)
@@ -125,13 +124,7 @@ func doDelete(ctx context.Context, client httpClient, req *http.Request, d Debug
 
 	defer resp.Body.Close()
 
-	err = checkStatusCode(resp, d)
-	if err != nil {
-		sweapError := &SweapError{}
-		e := newJSONParser(sweapError)(resp)
-		if e == nil {
-			return sweapError
-		}
+	if err = responseError(resp, d); err != nil {
 		return err
 	}
 
@@ -140,7 +133,8 @@ func doDelete(ctx context.Context, client httpClient, req *http.Request, d Debug
 
 // post JSON.
 func postJSON(ctx context.Context, client httpClient, endpoint string, json []byte, intf interface{}, d Debug) error {
-	reqBody := bytes.NewBuffer(json)
+	// a bytes.Reader lets the request body be rewound for retries
+	reqBody := bytes.NewReader(json)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, reqBody)
 	if err != nil {
 		return err
@@ -152,7 +146,8 @@ func postJSON(ctx context.Context, client httpClient, endpoint string, json []by
 
 // putJSON.
 func putJSON(ctx context.Context, client httpClient, endpoint string, json []byte, intf interface{}, d Debug) error {
-	reqBody := bytes.NewBuffer(json)
+	// a bytes.Reader lets the request body be rewound for retries
+	reqBody := bytes.NewReader(json)
 	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, reqBody)
 	if err != nil {
 		return err
@@ -214,6 +209,22 @@ func checkStatusCode(resp *http.Response, d Debug) error {
 	return nil
 }
 
+// responseError returns nil for successful responses. Otherwise it returns the *SweapError
+// sent by the server or, if the body does not carry one, a StatusCodeError.
+func responseError(resp *http.Response, d Debug) error {
+	err := checkStatusCode(resp, d)
+	if err == nil {
+		return nil
+	}
+
+	sweapError := &SweapError{}
+	if e := newJSONParser(sweapError)(resp); e != nil || sweapError.empty() {
+		return err
+	}
+	sweapError.HTTPStatus = resp.StatusCode
+	return sweapError
+}
+
 type responseParser func(*http.Response) error
 
 func newJSONParser(dst interface{}) responseParser {
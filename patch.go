@@ -0,0 +1,169 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// GuestPatch describes a partial update of a guest. Only fields that are not nil are changed.
+// CustomFields are changed per field: a nil value removes the field, fields not in the map are kept.
+//
+//	api.PatchGuest(id, sweap.GuestPatch{EntourageCount: sweap.Ptr(0), CategoryID: sweap.Ptr("")})
+type GuestPatch struct {
+	ExternalID      *string
+	FirstName       *string
+	LastName        *string
+	Email           *string
+	EntourageCount  *int
+	Comment         *string
+	CustomFields    map[string]*string
+	InvitationState *InvitationState
+	AttendanceState *AttendanceState
+	CategoryID      *string
+	ParentGuestID   *string
+}
+
+// Ptr returns a pointer to v, to fill a GuestPatch.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Fields returns the JSON names of the fields changed by the patch.
+func (p GuestPatch) Fields() []string {
+	var fields []string
+	add := func(set bool, name string) {
+		if set {
+			fields = append(fields, name)
+		}
+	}
+	add(p.ExternalID != nil, "externalId")
+	add(p.FirstName != nil, "firstName")
+	add(p.LastName != nil, "lastName")
+	add(p.Email != nil, "email")
+	add(p.EntourageCount != nil, "entourageCount")
+	add(p.Comment != nil, "comment")
+	add(p.CustomFields != nil, "customFields")
+	add(p.InvitationState != nil, "invitationState")
+	add(p.AttendanceState != nil, "attendanceState")
+	add(p.CategoryID != nil, "categoryId")
+	add(p.ParentGuestID != nil, "parentGuestId")
+	return fields
+}
+
+// Apply changes g according to the patch and reports whether anything changed.
+func (p GuestPatch) Apply(g *Guest) bool {
+	changed := false
+	set := func(dst *string, v *string) {
+		if v != nil && *dst != *v {
+			*dst, changed = *v, true
+		}
+	}
+
+	if p.ExternalID != nil && externalIDString(g.ExternalID) != *p.ExternalID {
+		g.ExternalID, changed = *p.ExternalID, true
+	}
+	set(&g.FirstName, p.FirstName)
+	set(&g.LastName, p.LastName)
+	set(&g.Email, p.Email)
+	set(&g.CategoryID, p.CategoryID)
+	set(&g.ParentGuestID, p.ParentGuestID)
+	if p.EntourageCount != nil && g.EntourageCount != *p.EntourageCount {
+		g.EntourageCount, changed = *p.EntourageCount, true
+	}
+	if p.Comment != nil && (g.Comment == nil || g.Comment != *p.Comment) {
+		g.Comment, changed = *p.Comment, true
+	}
+	if p.InvitationState != nil && g.InvitationState != *p.InvitationState {
+		g.InvitationState, changed = *p.InvitationState, true
+	}
+	if p.AttendanceState != nil && g.AttendanceState != *p.AttendanceState {
+		g.AttendanceState, changed = *p.AttendanceState, true
+	}
+
+	for id, v := range p.CustomFields {
+		old, ok := g.CustomFields[id]
+		switch {
+		case v == nil && ok:
+			delete(g.CustomFields, id)
+			changed = true
+		case v != nil && (!ok || old != *v):
+			if g.CustomFields == nil {
+				g.CustomFields = CustomFields{}
+			}
+			g.CustomFields[id] = *v
+			changed = true
+		}
+	}
+	return changed
+}
+
+// guestPayload marshals g for an update. The whole guest is sent, as PUT replaces the guest on
+// the server. Fields named in explicit are present even if they are empty and would be omitted
+// otherwise, so that clearing them takes effect; server managed timestamps are left out.
+func guestPayload(g Guest, explicit []string) ([]byte, error) {
+	if len(explicit) == 0 {
+		return json.Marshal(g)
+	}
+
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	delete(m, "createdAt")
+	delete(m, "updatedAt")
+
+	zero := map[string]interface{}{"email": "", "entourageCount": 0, "comment": nil, "customFields": CustomFields{}}
+	for _, f := range explicit {
+		if _, ok := m[f]; !ok {
+			m[f] = zero[f]
+		}
+	}
+	for _, f := range []string{"categoryId", "parentGuestId"} {
+		if m[f] == "" && contains(explicit, f) {
+			m[f] = nil
+		}
+	}
+	return json.Marshal(m)
+}
+
+// PatchGuest changes only the fields of the guest given in the patch, see GuestPatch.
+func (api *Client) PatchGuest(guestId string, p GuestPatch) (*Guest, error) {
+	return api.PatchGuestContext(context.Background(), guestId, p)
+}
+
+// PatchGuestContext changes the fields of the guest given in the patch with a custom context.
+// The patch is applied to the current guest, which is only updated if anything changes. The
+// merged guest is sent as a whole, so the fields not in the patch keep their current values.
+// The guest is read once and written right after, which keeps the time in which changes made
+// by someone else can be overwritten short.
+func (api *Client) PatchGuestContext(ctx context.Context, guestId string, p GuestPatch) (*Guest, error) {
+	// applying the patch takes no time, the guest just read is current
+	return api.modifyGuest(ctx, guestId, func(g *Guest) (bool, error) {
+		return p.Apply(g), nil
+	}, false, p.Fields()...)
+}
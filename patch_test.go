@@ -0,0 +1,112 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+// bodyRecorder records the bodies of PUT requests.
+type bodyRecorder struct {
+	puts []map[string]interface{}
+}
+
+func (br *bodyRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPut {
+		b, _ := io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		m := map[string]interface{}{}
+		json.Unmarshal(b, &m)
+		br.puts = append(br.puts, m)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestPatchGuest(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events:     sweap.Events{{ID: "event-1", Name: "Patch"}},
+		Categories: sweap.Categories{{ID: "cat-1", EventID: "event-1", Name: "VIP"}},
+		Guests: sweap.Guests{{ID: "g-1", EventID: "event-1", FirstName: "Anna", LastName: "Adams", EntourageCount: 2,
+			CategoryID: "cat-1", CustomFields: sweap.CustomFields{"title": "Dr.", "company": "ACME"}}},
+	})
+	defer srv.Close()
+	br := &bodyRecorder{}
+	api, _ := srv.Client(sweap.OptionHTTPClient(&http.Client{Transport: br}))
+
+	g, err := api.PatchGuest("g-1", sweap.GuestPatch{
+		EntourageCount: sweap.Ptr(0),
+		CategoryID:     sweap.Ptr(""),
+		CustomFields:   map[string]*string{"title": nil, "diet": sweap.Ptr("vegan")},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, g.EntourageCount)
+	assert.Equal(t, "Adams", g.LastName)
+
+	stored, _ := srv.Guest("g-1")
+	assert.Equal(t, "Anna", stored.FirstName)
+	assert.Equal(t, "", stored.CategoryID)
+	assert.Equal(t, sweap.CustomFields{"company": "ACME", "diet": "vegan"}, stored.CustomFields)
+
+	assert.Len(t, br.puts, 1)
+	put := br.puts[0]
+	assert.Contains(t, put, "entourageCount")
+	assert.Equal(t, 0.0, put["entourageCount"])
+	assert.Nil(t, put["categoryId"])
+	assert.Equal(t, map[string]interface{}{"company": "ACME", "diet": "vegan"}, put["customFields"])
+	assert.Equal(t, "Adams", put["lastName"], "the fields not in the patch are sent as they are")
+	assert.Equal(t, "Anna", put["firstName"])
+	assert.NotContains(t, put, "updatedAt")
+
+	// one read of the guest before the update
+	var methods []string
+	for _, r := range srv.Requests() {
+		methods = append(methods, r.Method)
+	}
+	assert.Equal(t, []string{http.MethodGet, http.MethodPut}, methods)
+
+	// nothing changes, nothing is sent
+	_, err = api.PatchGuest("g-1", sweap.GuestPatch{FirstName: sweap.Ptr("Anna"), CustomFields: map[string]*string{"title": nil}})
+	assert.Nil(t, err)
+	assert.Len(t, br.puts, 1)
+}
+
+func TestGuestPatchApply(t *testing.T) {
+	g := sweap.Guest{FirstName: "Anna", ExternalID: 7.0}
+	p := sweap.GuestPatch{ExternalID: sweap.Ptr("7"), Comment: sweap.Ptr("late arrival"), InvitationState: sweap.Ptr(sweap.ACCEPTED)}
+
+	assert.Equal(t, []string{"externalId", "comment", "invitationState"}, p.Fields())
+	assert.True(t, p.Apply(&g))
+	assert.Equal(t, 7.0, g.ExternalID) // same external ID in another form
+	assert.Equal(t, "late arrival", g.Comment)
+	assert.Equal(t, sweap.ACCEPTED, g.InvitationState)
+	assert.False(t, p.Apply(&g))
+	assert.Empty(t, sweap.GuestPatch{}.Fields())
+}
@@ -0,0 +1,165 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRateFraction limits how far the adaptive slowdown reduces the configured rate.
+const minRateFraction = 0.1
+
+// OptionRateLimit limits the client to perSecond requests per second with bursts of up to burst requests.
+// The limit is shared by all goroutines using the client. Whenever the server answers with
+// 429 Too Many Requests the rate is halved and then slowly recovered with every successful request.
+// A perSecond of zero or less means no limit, which is also the default.
+func OptionRateLimit(perSecond float64, burst int) func(*Client) {
+	return func(c *Client) {
+		if perSecond <= 0 {
+			c.limiter = nil
+			return
+		}
+		c.limiter = newRateLimiter(perSecond, burst)
+	}
+}
+
+// rateLimiter is a token bucket with an adaptive rate.
+type rateLimiter struct {
+	mu           sync.Mutex
+	rate         float64 // current tokens per second
+	maxRate      float64 // configured tokens per second
+	burst        float64
+	tokens       float64
+	last         time.Time
+	blockedUntil time.Time
+}
+
+func newRateLimiter(perSecond float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    perSecond,
+		maxRate: perSecond,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call. l.mu must be held.
+func (l *rateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+
+	// reserve a token, possibly going into debt, and wait until the debt is paid
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 && l.rate > 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	if now.Add(wait).Before(l.blockedUntil) {
+		wait = l.blockedUntil.Sub(now)
+	}
+	l.mu.Unlock()
+
+	if err := sleepContext(ctx, wait); err != nil {
+		// give back the reservation
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// slowDown halves the rate and pauses all requests for the given duration.
+func (l *rateLimiter) slowDown(pause time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.refill(now)
+	l.rate /= 2
+	if floor := l.maxRate * minRateFraction; l.rate < floor {
+		l.rate = floor
+	}
+	if until := now.Add(pause); until.After(l.blockedUntil) {
+		l.blockedUntil = until
+	}
+}
+
+// speedUp recovers the rate by 5% up to the configured rate.
+func (l *rateLimiter) speedUp() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate < l.maxRate {
+		l.refill(time.Now())
+		l.rate *= 1.05
+		if l.rate > l.maxRate {
+			l.rate = l.maxRate
+		}
+	}
+}
+
+// limitClient wraps a httpClient and waits on a rateLimiter before each request.
+type limitClient struct {
+	client  httpClient
+	limiter *rateLimiter
+	d       Debug
+}
+
+func (lc *limitClient) Do(req *http.Request) (*http.Response, error) {
+	if err := lc.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+
+	resp, err := lc.client.Do(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		pause, _ := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		lc.limiter.slowDown(pause)
+		lc.d.Debugf("rate limited on %v %v, slowing down", req.Method, req.URL.Path)
+	} else if resp.StatusCode < 400 {
+		lc.limiter.speedUp()
+	}
+	return resp, nil
+}
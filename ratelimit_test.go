@@ -0,0 +1,87 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterWaits(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		assert.Nil(t, l.Wait(ctx))
+	}
+	// 2 requests from the burst, 4 more at 100/s
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := newRateLimiter(0.001, 1)
+	assert.Nil(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, l.Wait(ctx))
+}
+
+func TestRateLimitUnlimited(t *testing.T) {
+	c := &Client{}
+	OptionRateLimit(10, 1)(c)
+	assert.NotNil(t, c.limiter)
+	OptionRateLimit(0, 1)(c)
+	assert.Nil(t, c.limiter)
+	OptionRateLimit(-1, 1)(c)
+	assert.Nil(t, c.limiter)
+}
+
+func TestRateLimiterSlowsDownOn429(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		rw.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	l := newRateLimiter(100, 10)
+	lc := &limitClient{client: http.DefaultClient, limiter: l, d: discard{}}
+
+	err := getResource(context.Background(), lc, server.URL, nil, nil, discard{})
+	assert.NotNil(t, err)
+	assert.Equal(t, 50.0, l.rate)
+
+	err = getResource(context.Background(), lc, server.URL, nil, &Guest{}, discard{})
+	assert.Nil(t, err)
+	assert.Greater(t, l.rate, 50.0)
+}
@@ -0,0 +1,226 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces every value removed from debug output.
+const Redacted = "***"
+
+// DefaultRedactedFields lists the JSON fields masked in debug output unless
+// configured otherwise with OptionRedactFields.
+var DefaultRedactedFields = []string{"email", "firstName", "lastName", "customFields"}
+
+// secretFields are JSON fields and form values that are masked in any case.
+var secretFields = []string{"client_secret", "access_token", "refresh_token", "id_token"}
+
+var (
+	sensitiveHeaderRe = regexp.MustCompile(`(?im)^((?:proxy-)?authorization|cookie|set-cookie)(:[ \t]*)[^\r\n]*`)
+	queryParamRe      = regexp.MustCompile(`(?m)(^|[?&\s])([A-Za-z_][A-Za-z0-9_.]*)=([^&\s#"]*)`)
+)
+
+// OptionRedactFields sets the JSON fields masked in debug output, replacing DefaultRedactedFields.
+// Field names are matched case insensitive at any nesting level, and as query and form parameters,
+// including search parameters like firstNameContains. Authorization headers, tokens and the
+// client secret are always masked.
+func OptionRedactFields(fields ...string) func(*Client) {
+	return func(c *Client) {
+		c.redact = newRedactor(fields...)
+	}
+}
+
+// redactor masks credentials and personal data in text written to the debug log.
+type redactor struct {
+	fields map[string]bool
+	secret string
+}
+
+// Redact masks credentials and the given fields in s the way debug output is masked, see
+// OptionRedactFields. Tools handling data of the API, like the recorder of package sweaptest,
+// use it to remove personal data.
+func Redact(s string, fields ...string) string {
+	return newRedactor(fields...).redact(s)
+}
+
+func newRedactor(fields ...string) redactor {
+	r := redactor{fields: make(map[string]bool)}
+	for _, f := range append(fields, secretFields...) {
+		r.fields[strings.ToLower(f)] = true
+	}
+	return r
+}
+
+// redact returns s with sensitive headers, secrets and the configured fields masked,
+// in JSON as well as in query strings and form bodies.
+func (r redactor) redact(s string) string {
+	if r.secret != "" {
+		s = strings.ReplaceAll(s, r.secret, Redacted)
+	}
+	s = sensitiveHeaderRe.ReplaceAllString(s, "${1}${2}"+Redacted)
+	s = r.maskParameters(s)
+	return r.maskJSONFields(s)
+}
+
+// maskParameters replaces the values of query and form parameters named like the configured fields.
+func (r redactor) maskParameters(s string) string {
+	if len(r.fields) == 0 || !strings.Contains(s, "=") {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range queryParamRe.FindAllStringSubmatchIndex(s, -1) {
+		name := strings.ToLower(s[m[4]:m[5]])
+		if !r.fields[name] && !r.fields[strings.TrimSuffix(name, "contains")] || m[6] == m[7] {
+			continue
+		}
+		b.WriteString(s[last:m[6]])
+		b.WriteString(Redacted)
+		last = m[7]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// maskJSONFields replaces the values of the configured fields in s. s does not have to be
+// valid JSON, so that HTTP dumps and truncated bodies are handled as well.
+func (r redactor) maskJSONFields(s string) string {
+	if len(r.fields) == 0 || !strings.Contains(s, `"`) {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	for i := 0; i < len(s); {
+		if s[i] != '"' {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := skipJSONString(s, i)
+		b.WriteString(s[i:end])
+
+		colon := skipWhitespace(s, end)
+		if end-i < 2 || colon >= len(s) || s[colon] != ':' || !r.fields[strings.ToLower(s[i+1:end-1])] {
+			i = end
+			continue
+		}
+
+		start := skipWhitespace(s, colon+1)
+		b.WriteString(s[end:start])
+		i = maskValue(&b, s, start)
+	}
+	return b.String()
+}
+
+// maskValue writes the value starting at i with every value but null masked. Objects and arrays
+// keep their structure, so that masked JSON can still be decoded. It returns the index after the value.
+func maskValue(b *strings.Builder, s string, i int) int {
+	end := skipJSONValue(s, i)
+	if i >= len(s) {
+		return end
+	}
+	if s[i] != '{' && s[i] != '[' {
+		if s[i:end] == "null" {
+			b.WriteString("null")
+		} else {
+			b.WriteString(`"` + Redacted + `"`)
+		}
+		return end
+	}
+
+	b.WriteByte(s[i])
+	for j := i + 1; j < end; {
+		switch {
+		case strings.ContainsRune(",:}] \t\r\n", rune(s[j])):
+			b.WriteByte(s[j])
+			j++
+		case s[j] == '"' && skipWhitespace(s, skipJSONString(s, j)) < len(s) && s[skipWhitespace(s, skipJSONString(s, j))] == ':':
+			// a key
+			k := skipJSONString(s, j)
+			b.WriteString(s[j:k])
+			j = k
+		default:
+			j = maskValue(b, s, j)
+		}
+	}
+	return end
+}
+
+// skipJSONString returns the index after the string starting with the quote at i.
+func skipJSONString(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// skipJSONValue returns the index after the value starting at i.
+func skipJSONValue(s string, i int) int {
+	if i >= len(s) {
+		return i
+	}
+
+	switch s[i] {
+	case '"':
+		return skipJSONString(s, i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(s); j++ {
+			switch s[j] {
+			case '"':
+				j = skipJSONString(s, j) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return j + 1
+				}
+			}
+		}
+		return len(s)
+	default:
+		j := i
+		for j < len(s) && !strings.ContainsRune(",}] \t\r\n", rune(s[j])) {
+			j++
+		}
+		return j
+	}
+}
+
+func skipWhitespace(s string, i int) int {
+	for i < len(s) && strings.ContainsRune(" \t\r\n", rune(s[i])) {
+		i++
+	}
+	return i
+}
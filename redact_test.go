@@ -0,0 +1,107 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactDump(t *testing.T) {
+	r := newRedactor(DefaultRedactedFields...)
+	r.secret = "s3cr3t-value"
+
+	dump := "POST /guests HTTP/1.1\r\n" +
+		"Host: api.sweap.io\r\n" +
+		"Authorization: Bearer e.e.Y-s-d-o\r\n" +
+		"Content-Type: application/json\r\n\r\n" +
+		`{"id":"1","firstName":"Theo","lastName":"Vassiliou","email":"theo@example.com",` +
+		`"comment":null,"customFields":{"title":"Dr. \"X\"","nested":{"a":[1,2]}},"ticketId":"B44ZZ2XBF69H",` +
+		`"parentGuestId":"s3cr3t-value","externalId":null}`
+
+	got := r.redact(dump)
+
+	assert.Contains(t, got, "Authorization: ***\r\n")
+	assert.Contains(t, got, `"firstName":"***"`)
+	assert.Contains(t, got, `"lastName":"***"`)
+	assert.Contains(t, got, `"email":"***"`)
+	assert.Contains(t, got, `"customFields":{"title":"***","nested":{"a":["***","***"]}}`)
+	assert.Contains(t, got, `"ticketId":"B44ZZ2XBF69H"`)
+	assert.Contains(t, got, `"parentGuestId":"***"`)
+	assert.Contains(t, got, `"externalId":null}`)
+	assert.NotContains(t, got, "Theo")
+	assert.NotContains(t, got, "e.e.Y-s-d-o")
+}
+
+func TestRedactTokens(t *testing.T) {
+	r := newRedactor()
+
+	got := r.redact(`grant_type=client_credentials&client_secret=abc&scope=x {"access_token": "e.e.Y", "email": "a@b.c"}`)
+	assert.Equal(t, `grant_type=client_credentials&client_secret=***&scope=x {"access_token": "***", "email": "a@b.c"}`, got)
+}
+
+func TestRedactQuery(t *testing.T) {
+	r := newRedactor(DefaultRedactedFields...)
+
+	got := r.redact("GET /core/v1/guests?email=secret.person%40example.com&eventId=e1&lastName=Smith&firstNameContains=Jo HTTP/1.1")
+	assert.Equal(t, "GET /core/v1/guests?email=***&eventId=e1&lastName=***&firstNameContains=*** HTTP/1.1", got)
+	assert.Equal(t, "https://api.sweap.io/core/v1/guests?eventId=e1&email=", r.redact("https://api.sweap.io/core/v1/guests?eventId=e1&email="))
+}
+
+func TestRedactTruncated(t *testing.T) {
+	r := newRedactor("email")
+	assert.Equal(t, `[{"email":"***"`, r.redact(`[{"email":"theo@exa`))
+	assert.Equal(t, `"email"`, r.redact(`"email"`))
+	r = newRedactor("customFields")
+	assert.Equal(t, `{"customFields":{"title":"***"`, r.redact(`{"customFields":{"title":"Dr. The`))
+}
+
+func TestDebugIsRedacted(t *testing.T) {
+	buf := bytes.NewBufferString("")
+	c := &Client{
+		debug:  true,
+		log:    internalLog{logger: log.New(buf, "", 0)},
+		redact: newRedactor(DefaultRedactedFields...),
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "https://api.sweap.io/core/v1/guests", strings.NewReader(`{"email":"theo@example.com"}`))
+	req.Header.Set("Authorization", "Bearer token")
+	logRequest(req, c)
+
+	assert.NotContains(t, buf.String(), "theo@example.com")
+	assert.NotContains(t, buf.String(), "Bearer token")
+
+	buf.Reset()
+	c.redact.secret = "s3cr3t"
+	params := url.Values{"eventId": {"e1"}, "email": {"secret.person@example.com"}, "lastName": {"Smith"}}
+	req, _ = http.NewRequest(http.MethodGet, "https://api.sweap.io/core/v1/guests?"+params.Encode(), nil)
+	logRequest(req, c)
+	assert.NotContains(t, buf.String(), "secret.person")
+	assert.NotContains(t, buf.String(), "Smith")
+	assert.Contains(t, buf.String(), "eventId=e1")
+}
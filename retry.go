@@ -0,0 +1,187 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy describes how often and how patiently a failed request is repeated.
+// A request is repeated if the server answers with a status code for which
+// StatusCodeError.Retryable returns true, or if the transport fails.
+type RetryPolicy struct {
+	MaxAttempts int           // number of attempts including the first one, <= 1 disables retries
+	BaseDelay   time.Duration // delay before the first retry, doubled on every further retry
+	MaxDelay    time.Duration // upper bound for a single delay, also caps Retry-After
+	Jitter      float64       // fraction (0..1) of each delay that is randomized
+}
+
+// NewRetryPolicy returns a RetryPolicy with sensible defaults:
+// 4 attempts, starting with 500ms and never waiting longer than 30s.
+func NewRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.5,
+	}
+}
+
+// OptionRetry enables retrying of failed requests with the given policy.
+func OptionRetry(p RetryPolicy) func(*Client) {
+	return func(c *Client) {
+		c.retry = &p
+	}
+}
+
+// backoff returns the delay before retry number attempt (starting with 1).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < attempt && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && d > 0 {
+		j := time.Duration(float64(d) * p.Jitter)
+		if j > 0 {
+			d = d - j + time.Duration(rand.Int63n(int64(j)+1))
+		}
+	}
+	return d
+}
+
+// retryClient wraps a httpClient and repeats requests according to a RetryPolicy.
+type retryClient struct {
+	client httpClient
+	policy RetryPolicy
+	d      Debug
+}
+
+func (rc *retryClient) Do(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			var err error
+			if r, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := rc.client.Do(r)
+		last := attempt >= rc.policy.MaxAttempts || !canRewind(req)
+		if last {
+			return resp, err
+		}
+
+		var wait time.Duration
+		if err != nil {
+			if req.Context().Err() != nil {
+				return nil, err
+			}
+			wait = rc.policy.backoff(attempt)
+			rc.d.Debugf("retrying %v %v after error: %v (attempt %d/%d, waiting %v)", req.Method, req.URL.Path, err, attempt, rc.policy.MaxAttempts, wait)
+		} else {
+			sce := StatusCodeError{Code: resp.StatusCode, Status: resp.Status}
+			if resp.StatusCode < 400 || !sce.Retryable() {
+				return resp, nil
+			}
+			wait = rc.policy.backoff(attempt)
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = ra
+				if rc.policy.MaxDelay > 0 && wait > rc.policy.MaxDelay {
+					wait = rc.policy.MaxDelay
+				}
+			}
+			rc.d.Debugf("retrying %v %v after %v (attempt %d/%d, waiting %v)", req.Method, req.URL.Path, resp.Status, attempt, rc.policy.MaxAttempts, wait)
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err := sleepContext(req.Context(), wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// canRewind reports whether the body of req can be sent a second time.
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest returns a copy of req with a fresh body.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+	return r, nil
+}
+
+// retryAfter parses the value of a Retry-After header, which is either
+// a number of seconds or a HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(v); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d or until ctx is done, whatever happens first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryRewindsBody(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, `{"firstName":"Theo"}`, string(body))
+		if calls < 3 {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.Write([]byte(`{"id":"1"}`))
+	}))
+	defer server.Close()
+
+	rc := &retryClient{client: http.DefaultClient, policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}, d: discard{}}
+	var g Guest
+	err := postJSON(context.Background(), rc, server.URL, []byte(`{"firstName":"Theo"}`), &g, discard{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, "1", g.ID)
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		rw.Header().Set("Retry-After", "0")
+		rw.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	rc := &retryClient{client: http.DefaultClient, policy: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Hour}, d: discard{}}
+	err := deleteResource(context.Background(), rc, server.URL, nil, discard{})
+
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, StatusCodeError{Code: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, err)
+}
+
+func TestRetryNotOnClientErrors(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	rc := &retryClient{client: http.DefaultClient, policy: NewRetryPolicy(), d: discard{}}
+	err := getResource(context.Background(), rc, server.URL, nil, nil, discard{})
+
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	d, ok := retryAfter("7", now)
+	assert.True(t, ok)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, ok = retryAfter(now.Add(time.Minute).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Minute, d)
+
+	_, ok = retryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, time.Second, p.backoff(1))
+	assert.Equal(t, 2*time.Second, p.backoff(2))
+	assert.Equal(t, 4*time.Second, p.backoff(3))
+	assert.Equal(t, 5*time.Second, p.backoff(4))
+
+	p.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		d := p.backoff(1)
+		assert.GreaterOrEqual(t, d, 500*time.Millisecond)
+		assert.LessOrEqual(t, d, time.Second)
+	}
+}
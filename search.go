@@ -34,6 +34,20 @@ const (
 	CLOSED EventState = "CLOSED"
 )
 
+// CanTransitionTo reports whether an event in state s may change to state to.
+// Events move from DRAFT to ACTIVE to CLOSED, keeping the state is always allowed.
+func (s EventState) CanTransitionTo(to EventState) bool {
+	switch {
+	case s == to:
+		return true
+	case s == DRAFT:
+		return to == ACTIVE
+	case s == ACTIVE:
+		return to == CLOSED
+	}
+	return false
+}
+
 type EventSearchParameter struct {
 	Id             string     // optional, filters equal event id
 	Name           string     // optional, filters equal case insensitive event name
@@ -128,11 +142,11 @@ func (api *Client) SearchGuests(id string, params GuestSearchParameter) (*Guests
 }
 
 type CategorySearchParameter struct {
-	GuestId      string     // optional, filters equal guest id
+	GuestId      string     // optional, filters equal category id
 	Name         string     // optional, filters equal case insensitive category name
-	ExternalID   string     // optional, filters equal guest externalId
-	CreatedAfter *time.Time // optional, filters greater than guest createdAt: ISO 8601 timestamp
-	UpdatedAfter *time.Time // optional, filters greater than guest updatedAt: ISO 8601 timestamp
+	ExternalID   string     // optional, filters equal category externalId
+	CreatedAfter *time.Time // optional, filters greater than category createdAt: ISO 8601 timestamp
+	UpdatedAfter *time.Time // optional, filters greater than category updatedAt: ISO 8601 timestamp
 }
 
 func NewCategorySearchParameter() CategorySearchParameter {
@@ -30,6 +30,7 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/clientcredentials"
 )
 
@@ -114,6 +115,12 @@ type Client struct {
 	log          ilogger
 	httpclient   httpClient
 	envFile      string
+	retry        *RetryPolicy
+	limiter      *rateLimiter
+	redact       redactor
+	baseClient   *http.Client
+	fieldDefs    *definitionCache
+	checkVersion bool
 }
 
 // NewSweap creates a new Sweap object with given credentials
@@ -127,11 +134,13 @@ func New(clientId, clientSecret string, options ...SweapOptions) (*Client, error
 		endpoint:     APIURL,
 		tokenurl:     TOKENURL,
 		log:          log.New(os.Stderr, "theovassiliou/sweap-go", log.LstdFlags|log.Lshortfile),
+		redact:       newRedactor(DefaultRedactedFields...),
 	}
 
 	for _, opt := range options {
 		opt(s)
 	}
+	s.redact.secret = s.clientSecret
 
 	c := clientcredentials.Config{
 		ClientID:     s.clientID,
@@ -141,9 +150,18 @@ func New(clientId, clientSecret string, options ...SweapOptions) (*Client, error
 	}
 
 	ctx := context.Background()
+	if s.baseClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, s.baseClient)
+	}
 	client := c.Client(ctx)
 
 	s.httpclient = client
+	if s.limiter != nil {
+		s.httpclient = &limitClient{client: s.httpclient, limiter: s.limiter, d: s}
+	}
+	if s.retry != nil {
+		s.httpclient = &retryClient{client: s.httpclient, policy: *s.retry, d: s}
+	}
 
 	if c.TokenURL == TOKENURL_DEV && !s.checkCredentials() {
 		return nil, fmt.Errorf("authorization at endopoint %v failed. Check credentials", s.tokenurl)
@@ -228,6 +246,12 @@ func OptionTOKENURL(u string) func(*Client) {
 	return func(c *Client) { c.tokenurl = u }
 }
 
+// OptionHTTPClient sets the http.Client used for all requests, including the token requests.
+// Useful to plug in custom transports, e.g. for recording and replaying requests in tests.
+func OptionHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) { c.baseClient = hc }
+}
+
 // ----- Communication -------
 
 // get a Sweap web method.
@@ -236,17 +260,17 @@ func (api *Client) getMethod(ctx context.Context, path string, values url.Values
 }
 
 // ----- Logging ------
-// Debugf print a formatted debug line.
+// Debugf print a formatted debug line. Credentials and personal data are masked.
 func (api *Client) Debugf(format string, v ...interface{}) {
 	if api.debug {
-		api.log.Output(2, fmt.Sprintf(format, v...))
+		api.log.Output(2, api.redact.redact(fmt.Sprintf(format, v...)))
 	}
 }
 
-// Debugln print a debug line.
+// Debugln print a debug line. Credentials and personal data are masked.
 func (api *Client) Debugln(v ...interface{}) {
 	if api.debug {
-		api.log.Output(2, fmt.Sprintln(v...))
+		api.log.Output(2, api.redact.redact(fmt.Sprintln(v...)))
 	}
 }
 
@@ -0,0 +1,411 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package sweapcsv imports guests from CSV files, e.g. spreadsheets handed over by organizers.
+//
+// Columns are mapped to guest fields and custom fields by a declarative Mapping. Rows are validated
+// and rejected rows are reported with their line number; the valid rows can be created one by one
+// or uploaded with a guest bulk import:
+//
+//	res, err := sweapcsv.Read(f, sweapcsv.Options{
+//		EventID:  "event-1",
+//		Encoding: sweapcsv.LATIN1,
+//		Comma:    ';',
+//		Mapping: sweapcsv.Mapping{
+//			{Header: "Vorname", Field: "firstName", Required: true},
+//			{Header: "Nachname", Field: "lastName", Required: true},
+//			{Header: "E-Mail", Field: "email"},
+//			{Header: "Menü", Field: "customFields.Menu"},
+//		},
+//	})
+//	for _, e := range res.Errors {
+//		fmt.Println(e)
+//	}
+//	result, err := res.Import(ctx, api, "event-1", sweap.ImportOptions{})
+package sweapcsv
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+const customFieldPrefix = "customFields."
+
+// Column maps a CSV column to a guest field.
+type Column struct {
+	Header   string // header of the column, matched ignoring case and surrounding spaces
+	Field    string // JSON name of the guest field, e.g. "firstName", or "customFields.<ID or name>"
+	Required bool   // rows without a value are rejected
+	Default  string // value used for empty cells
+	// Transform optionally converts the value of a cell before it is assigned, e.g. to map
+	// "yes" to "true". An error rejects the row.
+	Transform func(string) (string, error)
+}
+
+// Mapping describes the columns of a CSV file to import. Columns not in the mapping are ignored.
+type Mapping []Column
+
+// Options control Read. Zero values select the defaults.
+type Options struct {
+	Comma      rune     // field delimiter, default ','
+	Encoding   Encoding // default UTF8
+	LazyQuotes bool     // accept quotes in unquoted fields, see csv.Reader
+	// Mapping of the columns. Without a mapping every column named like a guest field
+	// (e.g. "firstName", "First Name" or "first_name") or a custom field is imported.
+	Mapping Mapping
+	EventID string // event ID of the guests, unless mapped from a column
+	// CustomFieldDefinitions of the event. If given, custom fields can be mapped by name and
+	// their values are validated.
+	CustomFieldDefinitions []sweap.CustomFieldDefinitions
+	// Validate optionally checks a guest after its fields have been assigned. An error rejects the row.
+	Validate func(g *sweap.Guest) error
+}
+
+// Row is a valid row of a CSV file.
+type Row struct {
+	Line  int // line of the row in the CSV file, starting with 1 for the header
+	Guest sweap.Guest
+}
+
+// RowError describes why a row has been rejected.
+type RowError struct {
+	Line   int
+	Column string // header of the offending column, if any
+	Err    error
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d, column %q: %v", e.Line, e.Column, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// Result holds the valid rows and the errors of the rejected rows, both in the order of the file.
+type Result struct {
+	Rows   []Row
+	Errors []RowError
+}
+
+// Guests returns the guests of the valid rows.
+func (res *Result) Guests() sweap.Guests {
+	guests := make(sweap.Guests, len(res.Rows))
+	for i, r := range res.Rows {
+		guests[i] = r.Guest
+	}
+	return guests
+}
+
+// Create creates the guests of the valid rows one by one and returns the created guests.
+// Guests that could not be created are reported with the line of their row.
+func (res *Result) Create(ctx context.Context, api *sweap.Client) (sweap.Guests, []RowError) {
+	var created sweap.Guests
+	var errs []RowError
+	for _, r := range res.Rows {
+		g, err := api.CreateGuestContext(ctx, r.Guest)
+		if err != nil {
+			errs = append(errs, RowError{Line: r.Line, Err: err})
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		created = append(created, *g)
+	}
+	return created, errs
+}
+
+// Import uploads the guests of the valid rows with a guest bulk import, see sweap.Client.ImportGuests.
+func (res *Result) Import(ctx context.Context, api *sweap.Client, eventId string, opts sweap.ImportOptions) (*sweap.ImportResult, error) {
+	return api.ImportGuests(ctx, eventId, res.Guests(), opts)
+}
+
+// Read reads guests from CSV. The first line must hold the column headers.
+// Invalid rows do not stop reading but are reported in Result.Errors. An error is returned if the
+// header does not match the mapping or the input cannot be read.
+func Read(r io.Reader, opts Options) (*Result, error) {
+	cr := csv.NewReader(decode(r, opts.Encoding))
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+	cr.LazyQuotes = opts.LazyQuotes
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, sweap.SweapLibraryError{Message: "no CSV header found"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	cols, err := opts.columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var pe *csv.ParseError
+		if errors.As(err, &pe) {
+			res.Errors = append(res.Errors, RowError{Line: pe.Line, Err: pe.Err})
+			continue
+		}
+		if err != nil {
+			return res, err
+		}
+		line, _ := cr.FieldPos(0)
+		if blank(record) {
+			continue
+		}
+		if len(record) != len(header) {
+			res.Errors = append(res.Errors, RowError{Line: line, Err: fmt.Errorf("%d fields instead of %d", len(record), len(header))})
+			continue
+		}
+
+		g, errs := opts.guest(cols, record)
+		for i := range errs {
+			errs[i].Line = line
+		}
+		if len(errs) > 0 {
+			res.Errors = append(res.Errors, errs...)
+			continue
+		}
+		res.Rows = append(res.Rows, Row{Line: line, Guest: g})
+	}
+	return res, nil
+}
+
+// column is a mapped column with its position in the CSV file.
+type column struct {
+	Column
+	index       int
+	set         func(g *sweap.Guest, v string) error
+	customField string // ID of the custom field, if the column is mapped to one
+}
+
+// columns resolves the mapping against the header of the CSV file.
+func (opts Options) columns(header []string) ([]column, error) {
+	if len(opts.Mapping) == 0 {
+		var cols []column
+		for i, h := range header {
+			if name := fieldName(h); name != "" {
+				cols = append(cols, column{Column: Column{Header: h, Field: name}, index: i, set: guestFields[name]})
+			} else if len(opts.CustomFieldDefinitions) > 0 {
+				if c, err := opts.customFieldColumn(Column{Header: h, Field: customFieldPrefix + strings.TrimSpace(h)}, i); err == nil {
+					cols = append(cols, c)
+				}
+			}
+		}
+		return cols, nil
+	}
+
+	positions := map[string]int{}
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if _, ok := positions[h]; !ok {
+			positions[h] = i
+		}
+	}
+	cols := make([]column, 0, len(opts.Mapping))
+	for _, c := range opts.Mapping {
+		i, ok := positions[strings.ToLower(strings.TrimSpace(c.Header))]
+		if !ok {
+			return nil, sweap.SweapLibraryError{Message: fmt.Sprintf("column %q not found in CSV header", c.Header)}
+		}
+		if set, ok := guestFields[c.Field]; ok {
+			cols = append(cols, column{Column: c, index: i, set: set})
+			continue
+		}
+		if !strings.HasPrefix(c.Field, customFieldPrefix) {
+			return nil, sweap.SweapLibraryError{Message: fmt.Sprintf("column %q is mapped to unknown guest field %q", c.Header, c.Field)}
+		}
+		col, err := opts.customFieldColumn(c, i)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// fieldName returns the guest field named like a header, e.g. "firstName" for "First Name".
+func fieldName(header string) string {
+	key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(header)))
+	for name := range guestFields {
+		if strings.ToLower(name) == key {
+			return name
+		}
+	}
+	return ""
+}
+
+// customFieldColumn maps a column to a custom field given by ID or, if definitions are given, by name.
+func (opts Options) customFieldColumn(c Column, index int) (column, error) {
+	id := strings.TrimPrefix(c.Field, customFieldPrefix)
+	if len(opts.CustomFieldDefinitions) > 0 {
+		name := id
+		id = ""
+		for _, d := range opts.CustomFieldDefinitions {
+			if d.ID == name || strings.EqualFold(d.Name, name) {
+				id = d.ID
+				break
+			}
+		}
+		if id == "" {
+			return column{}, sweap.SweapLibraryError{Message: fmt.Sprintf("unknown custom field %q", name)}
+		}
+	}
+	set := func(g *sweap.Guest, v string) error {
+		if g.CustomFields == nil {
+			g.CustomFields = sweap.CustomFields{}
+		}
+		g.CustomFields[id] = v
+		return nil
+	}
+	return column{Column: c, index: index, set: set, customField: id}, nil
+}
+
+// guest converts a record into a guest. The returned errors lack the line.
+func (opts Options) guest(cols []column, record []string) (sweap.Guest, []RowError) {
+	g := sweap.Guest{EventID: opts.EventID}
+	var errs []RowError
+	headers := map[string]string{} // column headers by custom field, to report validation errors
+	for _, c := range cols {
+		v := strings.TrimSpace(record[c.index])
+		if opts.Encoding == UTF8 && !utf8.ValidString(v) {
+			errs = append(errs, RowError{Column: c.Header, Err: errors.New("invalid UTF-8, is the file encoded in Latin-1?")})
+			continue
+		}
+		if v == "" {
+			v = c.Default
+		}
+		if v != "" && c.Transform != nil {
+			var err error
+			if v, err = c.Transform(v); err != nil {
+				errs = append(errs, RowError{Column: c.Header, Err: err})
+				continue
+			}
+		}
+		if v == "" {
+			if c.Required {
+				errs = append(errs, RowError{Column: c.Header, Err: errors.New("value required")})
+			}
+			continue
+		}
+		if err := c.set(&g, v); err != nil {
+			errs = append(errs, RowError{Column: c.Header, Err: err})
+		}
+		if c.customField != "" {
+			headers[customFieldPrefix+c.customField] = c.Header
+		}
+	}
+	if len(errs) > 0 {
+		return g, errs
+	}
+
+	if len(opts.CustomFieldDefinitions) > 0 {
+		var ve *sweap.ValidationError
+		if err := sweap.ValidateCustomFields(opts.CustomFieldDefinitions, g.CustomFields); errors.As(err, &ve) {
+			for _, f := range ve.Fields {
+				errs = append(errs, RowError{Column: headers[f.Field], Err: errors.New(f.Message)})
+			}
+			return g, errs
+		}
+	}
+	if opts.Validate != nil {
+		if err := opts.Validate(&g); err != nil {
+			errs = append(errs, RowError{Err: err})
+		}
+	}
+	return g, errs
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// guestFields assigns the value of a cell to the guest field with the JSON name of the key.
+var guestFields = map[string]func(g *sweap.Guest, v string) error{
+	"eventId":       func(g *sweap.Guest, v string) error { g.EventID = v; return nil },
+	"externalId":    func(g *sweap.Guest, v string) error { g.ExternalID = v; return nil },
+	"firstName":     func(g *sweap.Guest, v string) error { g.FirstName = v; return nil },
+	"lastName":      func(g *sweap.Guest, v string) error { g.LastName = v; return nil },
+	"comment":       func(g *sweap.Guest, v string) error { g.Comment = v; return nil },
+	"categoryId":    func(g *sweap.Guest, v string) error { g.CategoryID = v; return nil },
+	"parentGuestId": func(g *sweap.Guest, v string) error { g.ParentGuestID = v; return nil },
+	"ticketId":      func(g *sweap.Guest, v string) error { g.TicketID = v; return nil },
+	"invitationId":  func(g *sweap.Guest, v string) error { g.InvitationID = v; return nil },
+	"email": func(g *sweap.Guest, v string) error {
+		if a, err := mail.ParseAddress(v); err != nil || a.Address != v {
+			return fmt.Errorf("invalid email address %q", v)
+		}
+		g.Email = v
+		return nil
+	},
+	"entourageCount": func(g *sweap.Guest, v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid entourage count %q", v)
+		}
+		g.EntourageCount = n
+		return nil
+	},
+	"invitationState": func(g *sweap.Guest, v string) error {
+		s := sweap.InvitationState(strings.ToUpper(v))
+		switch s {
+		case sweap.NONE, sweap.NO_REPLY, sweap.ACCEPTED, sweap.DECLINED:
+			g.InvitationState = s
+			return nil
+		}
+		return fmt.Errorf("invalid invitation state %q", v)
+	},
+	"attendanceState": func(g *sweap.Guest, v string) error {
+		s := sweap.AttendanceState(strings.ToUpper(v))
+		switch s {
+		case sweap.NONEATTENDANCE, sweap.PRESENT, sweap.GONE:
+			g.AttendanceState = s
+			return nil
+		}
+		return fmt.Errorf("invalid attendance state %q", v)
+	},
+}
@@ -0,0 +1,182 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweapcsv_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweapcsv"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+var defs = []sweap.CustomFieldDefinitions{
+	{ID: "cf-seats", Name: "Seats", Type: "NUMBER"},
+	{ID: "cf-menu", Name: "Menu", Type: "SELECT", Options: []interface{}{"meat", "vegetarian"}},
+}
+
+func TestRead(t *testing.T) {
+	in := "\xEF\xBB\xBFVorname;Nachname;E-Mail;Plätze;Menü;Status\n" +
+		"Anna;Adams;anna@example.com;2;vegetarian;accepted\n" +
+		"\n" +
+		";;;;;\n" +
+		"Ben;\"Berg\nBerlin\";ben(at)example.com;x;fish;\n" +
+		"Carl;;carl@example.com;1;;maybe\n" +
+		"Dora;Dorn;dora@example.com;;meat\n" +
+		"Emil;Ernst;;3;;yes\n"
+
+	res, err := sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{
+		Comma:                  ';',
+		EventID:                "event-1",
+		CustomFieldDefinitions: defs,
+		Mapping: sweapcsv.Mapping{
+			{Header: "vorname", Field: "firstName", Required: true},
+			{Header: "Nachname", Field: "lastName", Required: true},
+			{Header: "E-Mail", Field: "email"},
+			{Header: "Plätze", Field: "customFields.seats"},
+			{Header: "Menü", Field: "customFields.cf-menu", Default: "meat"},
+			{Header: "Status", Field: "invitationState", Transform: func(v string) (string, error) {
+				if strings.EqualFold(v, "yes") {
+					return "ACCEPTED", nil
+				}
+				return v, nil
+			}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []sweapcsv.Row{
+		{Line: 2, Guest: sweap.Guest{EventID: "event-1", FirstName: "Anna", LastName: "Adams", Email: "anna@example.com",
+			CustomFields: sweap.CustomFields{"cf-seats": "2", "cf-menu": "vegetarian"}, InvitationState: sweap.ACCEPTED}},
+		{Line: 9, Guest: sweap.Guest{EventID: "event-1", FirstName: "Emil", LastName: "Ernst",
+			CustomFields: sweap.CustomFields{"cf-seats": "3", "cf-menu": "meat"}, InvitationState: sweap.ACCEPTED}},
+	}, res.Rows)
+
+	msgs := []string{}
+	for _, e := range res.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		`line 5, column "E-Mail": invalid email address "ben(at)example.com"`,
+		`line 7, column "Nachname": value required`,
+		`line 7, column "Status": invalid invitation state "maybe"`,
+		"line 8: 5 fields instead of 6",
+	}, msgs, "custom fields are only validated once the other fields are valid")
+	assert.Equal(t, sweap.Guests{res.Rows[0].Guest, res.Rows[1].Guest}, res.Guests())
+}
+
+func TestReadCustomFieldValidation(t *testing.T) {
+	in := "First Name,last_name,Seats,Menu,Shoe size\nAnna,Adams,two,fish,42\n"
+	res, err := sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{CustomFieldDefinitions: defs})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Rows)
+	assert.Len(t, res.Errors, 2)
+	for _, e := range res.Errors {
+		assert.Equal(t, 2, e.Line)
+		assert.Contains(t, []string{"Seats", "Menu"}, e.Column)
+	}
+
+	// without mapping every column named like a guest field is imported
+	in = "First Name,last_name,EMAIL,entourage count,unknown\nAnna,Adams,anna@example.com,2,x\n"
+	res, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{EventID: "event-1"})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, sweap.Guest{EventID: "event-1", FirstName: "Anna", LastName: "Adams", Email: "anna@example.com", EntourageCount: 2}, res.Rows[0].Guest)
+}
+
+func TestReadEncoding(t *testing.T) {
+	in := "firstName,lastName\nJ\xfcrgen,M\xfcller\n"
+
+	res, err := sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{Encoding: sweapcsv.LATIN1})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, "Jürgen", res.Rows[0].Guest.FirstName)
+	assert.Equal(t, "Müller", res.Rows[0].Guest.LastName)
+
+	res, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{})
+	assert.Nil(t, err)
+	assert.Empty(t, res.Rows)
+	assert.Len(t, res.Errors, 2)
+	assert.Contains(t, res.Errors[0].Error(), "Latin-1")
+}
+
+func TestReadErrors(t *testing.T) {
+	_, err := sweapcsv.Read(strings.NewReader(""), sweapcsv.Options{})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+
+	in := "firstName,lastName\nAnna,Adams\n"
+	_, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{Mapping: sweapcsv.Mapping{{Header: "email", Field: "email"}}})
+	assert.ErrorContains(t, err, `column "email" not found`)
+	_, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{Mapping: sweapcsv.Mapping{{Header: "firstName", Field: "nickName"}}})
+	assert.ErrorContains(t, err, `unknown guest field "nickName"`)
+	_, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{CustomFieldDefinitions: defs, Mapping: sweapcsv.Mapping{{Header: "lastName", Field: "customFields.Shoe size"}}})
+	assert.ErrorContains(t, err, `unknown custom field "Shoe size"`)
+
+	// malformed rows are reported, reading goes on
+	in = "firstName,lastName\nAn\"na,Adams\nBen,Berg\n"
+	res, err := sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{})
+	assert.Nil(t, err)
+	assert.Len(t, res.Errors, 1)
+	assert.Equal(t, 2, res.Errors[0].Line)
+	assert.Len(t, res.Rows, 1)
+	assert.Equal(t, 3, res.Rows[0].Line)
+
+	in = "firstName,lastName\nAnna,Adams\n"
+	notAnna := errors.New("no Anna")
+	res, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{Validate: func(g *sweap.Guest) error {
+		if g.FirstName == "Anna" {
+			return notAnna
+		}
+		return nil
+	}})
+	assert.Nil(t, err)
+	assert.ErrorIs(t, res.Errors[0], notAnna)
+	assert.Equal(t, "line 2: no Anna", res.Errors[0].Error())
+}
+
+func TestCreateAndImport(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "event-1", Name: "CSV"}}})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	in := "eventId,firstName,lastName\nevent-1,Anna,Adams\nevent-2,Ben,Berg\n"
+	res, err := sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{})
+	assert.Nil(t, err)
+	created, errs := res.Create(context.Background(), api)
+	assert.Len(t, created, 1)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 3, errs[0].Line)
+	assert.Len(t, srv.Guests("event-1"), 1)
+
+	in = "firstName,lastName\nCarl,Cole\nDora,Dorn\n"
+	res, err = sweapcsv.Read(strings.NewReader(in), sweapcsv.Options{EventID: "event-1"})
+	assert.Nil(t, err)
+	result, err := res.Import(context.Background(), api, "event-1", sweap.ImportOptions{PollInterval: time.Millisecond})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, result.Guests)
+	assert.Len(t, srv.Guests("event-1"), 3)
+}
@@ -0,0 +1,171 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"net/http"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+// handleBulkImports implements the guest bulk import life cycle:
+// UPLOAD_STARTED -> UPLOAD_FINISHED -> IMPORT_STARTED -> IMPORT_FINISHED.
+// Once the upload is finished the import advances with every request reading the import.
+func (s *Server) handleBulkImports(rw http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		f := newFilter(r.URL.Query())
+		imports := sweap.GuestBulkImports{}
+		for _, b := range s.bulkImports.list() {
+			if f.equal("id", b.ID) && f.equal("eventId", b.EventId) && f.equal("state", string(*b.State)) &&
+				f.equal("externalId", externalID(b.ExternalID)) &&
+				f.after("createdAfter", b.CreatedAt) && f.after("updatedAfter", b.UpdatedAt) {
+				imports = append(imports, b.GuestBulkImport)
+			}
+		}
+		if f.err != nil {
+			writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, f.err.Error())
+			return
+		}
+		writeJSON(rw, http.StatusOK, imports)
+
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var gbi sweap.GuestBulkImport
+		if !decode(rw, r, &gbi) {
+			return
+		}
+		if gbi.EventId == "" {
+			writeError(rw, http.StatusBadRequest, sweap.VALIDATION_EXCEPTION, "eventId must not be empty")
+			return
+		}
+		if _, ok := s.events.get(gbi.EventId); !ok {
+			writeError(rw, http.StatusNotFound, sweap.NOT_FOUND, "event "+gbi.EventId+" not found")
+			return
+		}
+		gbi.ID, gbi.Version, gbi.CreatedAt, gbi.UpdatedAt, gbi.State = "", 0, nil, nil, nil
+		writeJSON(rw, http.StatusCreated, s.createBulkImport(gbi))
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		b, ok := s.advance(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, b.GuestBulkImport)
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if !s.bulkImports.delete(path[0]) {
+			notFound(rw, r)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+
+	case len(path) == 2 && path[1] == "state" && r.Method == http.MethodGet:
+		b, ok := s.advance(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, sweap.GuestBulkImportState{ID: b.ID, State: *b.State})
+
+	case len(path) == 2 && path[1] == "state" && r.Method == http.MethodPut:
+		b, ok := s.bulkImports.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		var state sweap.GuestBulkImportState
+		if !decode(rw, r, &state) {
+			return
+		}
+		if state.State != sweap.UPLOADFINISHED || *b.State != sweap.UPLOADSTARTED {
+			writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, "cannot change state from "+string(*b.State)+" to "+string(state.State))
+			return
+		}
+		s.setState(&b, sweap.UPLOADFINISHED)
+		rw.WriteHeader(http.StatusOK)
+
+	case len(path) == 2 && path[1] == "upload-batch" && r.Method == http.MethodPut:
+		b, ok := s.bulkImports.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		var guests sweap.Guests
+		if !decode(rw, r, &guests) {
+			return
+		}
+		if *b.State != sweap.UPLOADSTARTED {
+			writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, "upload of guest bulk import "+b.ID+" already finished")
+			return
+		}
+		all := append(append(sweap.Guests{}, *b.Guests...), guests...)
+		b.Guests = &all
+		s.bulkImports.put(b.ID, b)
+		rw.WriteHeader(http.StatusOK)
+
+	default:
+		methodNotAllowed(rw, r)
+	}
+}
+
+// advance moves a finished upload one step further towards IMPORT_FINISHED. s.mu must be held.
+func (s *Server) advance(id string) (bulkImport, bool) {
+	b, ok := s.bulkImports.get(id)
+	if !ok {
+		return b, false
+	}
+
+	switch *b.State {
+	case sweap.UPLOADFINISHED:
+		b.polls = 1
+		if s.ImportPolls <= 0 {
+			s.finishImport(&b)
+		} else {
+			s.setState(&b, sweap.IMPORTSTARTED)
+		}
+	case sweap.IMPORTSTARTED:
+		if b.polls >= s.ImportPolls {
+			s.finishImport(&b)
+		} else {
+			b.polls++
+			s.bulkImports.put(b.ID, b)
+		}
+	}
+	return b, true
+}
+
+// finishImport creates the uploaded guests in the event. s.mu must be held.
+func (s *Server) finishImport(b *bulkImport) {
+	for _, g := range *b.Guests {
+		g.ID, g.Version, g.CreatedAt, g.UpdatedAt = "", 0, nil, nil
+		g.EventID = b.EventId
+		s.createGuest(g)
+	}
+	s.setState(b, sweap.IMPORTFINISHED)
+}
+
+func (s *Server) setState(b *bulkImport, state sweap.GuestBulkImportStatus) {
+	b.State = &state
+	s.touch(&b.Version, &b.UpdatedAt)
+	s.bulkImports.put(b.ID, *b)
+}
@@ -0,0 +1,91 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"net/http"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+func (s *Server) handleCategories(rw http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		f := newFilter(r.URL.Query())
+		categories := sweap.Categories{}
+		for _, c := range s.categories.list() {
+			if f.equal("eventId", c.EventID) && f.equal("id", c.ID) && f.equal("name", c.Name) {
+				categories = append(categories, c)
+			}
+		}
+		writeJSON(rw, http.StatusOK, categories)
+
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var c sweap.Category
+		if !decode(rw, r, &c) {
+			return
+		}
+		if c.EventID == "" || c.Name == "" {
+			writeError(rw, http.StatusBadRequest, sweap.VALIDATION_EXCEPTION, "eventId and name must not be empty")
+			return
+		}
+		if _, ok := s.events.get(c.EventID); !ok {
+			writeError(rw, http.StatusNotFound, sweap.NOT_FOUND, "event "+c.EventID+" not found")
+			return
+		}
+		c.ID = newID()
+		s.categories.put(c.ID, c)
+		writeJSON(rw, http.StatusCreated, c)
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		c, ok := s.categories.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, c)
+
+	case len(path) == 1 && r.Method == http.MethodPut:
+		old, ok := s.categories.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		var c sweap.Category
+		if !decode(rw, r, &c) {
+			return
+		}
+		c.ID = old.ID
+		s.categories.put(c.ID, c)
+		writeJSON(rw, http.StatusOK, c)
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if !s.categories.delete(path[0]) {
+			notFound(rw, r)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+
+	default:
+		methodNotAllowed(rw, r)
+	}
+}
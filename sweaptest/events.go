@@ -0,0 +1,205 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"net/http"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+func (s *Server) handleEvents(rw http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		if events, ok := s.searchEvents(rw, r); ok {
+			writeJSON(rw, http.StatusOK, events)
+		}
+
+	case len(path) == 1 && path[0] == "paginated" && r.Method == http.MethodGet:
+		events, ok := s.searchEvents(rw, r)
+		if !ok {
+			return
+		}
+		f := newFilter(r.URL.Query())
+		p := sweap.EventPages{Status: string(sweap.OK)}
+		p.Content, p.Pageable = paginate(events, f.intValue("page", 0), f.intValue("size", 100))
+		writeJSON(rw, http.StatusOK, p)
+
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var e sweap.Event
+		if !decode(rw, r, &e) {
+			return
+		}
+		if e.Name == "" {
+			writeError(rw, http.StatusBadRequest, sweap.VALIDATION_EXCEPTION, "name must not be empty")
+			return
+		}
+		e.ID, e.Version, e.CreatedAt, e.UpdatedAt = "", 0, nil, nil
+		if e.State == "" {
+			e.State = string(sweap.DRAFT)
+		}
+		s.stamp(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+		assignFieldIDs(e.CustomFieldDefinitions)
+		s.events.put(e.ID, e)
+		writeJSON(rw, http.StatusCreated, e)
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		e, ok := s.events.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, e)
+
+	case len(path) == 1 && r.Method == http.MethodPut:
+		old, ok := s.events.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		var e sweap.Event
+		if !decode(rw, r, &e) {
+			return
+		}
+		e.ID, e.Version, e.CreatedAt, e.UpdatedAt = old.ID, old.Version, old.CreatedAt, old.UpdatedAt
+		s.touch(&e.Version, &e.UpdatedAt)
+		assignFieldIDs(e.CustomFieldDefinitions)
+		s.events.put(e.ID, e)
+		writeJSON(rw, http.StatusOK, e)
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if !s.events.delete(path[0]) {
+			notFound(rw, r)
+			return
+		}
+		for _, g := range s.guests.list() {
+			if g.EventID == path[0] {
+				s.guests.delete(g.ID)
+			}
+		}
+		for _, c := range s.categories.list() {
+			if c.EventID == path[0] {
+				s.categories.delete(c.ID)
+			}
+		}
+		rw.WriteHeader(http.StatusOK)
+
+	default:
+		methodNotAllowed(rw, r)
+	}
+}
+
+// searchEvents applies the event filters of the query. It writes an error and returns false for invalid queries.
+func (s *Server) searchEvents(rw http.ResponseWriter, r *http.Request) (sweap.Events, bool) {
+	f := newFilter(r.URL.Query())
+	events := sweap.Events{}
+	for _, e := range s.events.list() {
+		if f.equal("id", e.ID) && f.equal("name", e.Name) && f.contains("nameContains", e.Name) &&
+			f.equal("state", e.State) && f.equal("externalId", externalID(e.ExternalID)) &&
+			f.after("startDateAfter", &e.StartDate) && f.after("endDateAfter", &e.EndDate) &&
+			f.after("createdAfter", e.CreatedAt) && f.after("updatedAfter", e.UpdatedAt) {
+			events = append(events, e)
+		}
+	}
+	if f.err != nil {
+		writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, f.err.Error())
+		return nil, false
+	}
+	return events, true
+}
+
+func (s *Server) handleEventStatistics(rw http.ResponseWriter, r *http.Request, path []string) {
+	if r.Method != http.MethodGet || len(path) > 1 {
+		methodNotAllowed(rw, r)
+		return
+	}
+
+	if len(path) == 1 {
+		e, ok := s.events.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, s.statistic(e))
+		return
+	}
+
+	f := newFilter(r.URL.Query())
+	statistics := sweap.EventStatistics{}
+	for _, e := range s.events.list() {
+		st := s.statistic(e)
+		if f.equal("id", e.ID) && f.equal("externalId", externalID(e.ExternalID)) &&
+			f.after("createdAfter", e.CreatedAt) && f.after("updatedAfter", e.UpdatedAt) &&
+			f.min("minGuestCount", st.GuestCount) && f.max("maxGuestCount", st.GuestCount) &&
+			f.min("minAcceptedCount", st.AcceptedCount) && f.max("maxAcceptedCount", st.AcceptedCount) &&
+			f.min("minDeclinedCount", st.DeclindedCount) && f.max("maxDeclinedCount", st.DeclindedCount) &&
+			f.min("minNoReplyCount", st.NoReplyCount) && f.max("maxNoReplyCount", st.NoReplyCount) &&
+			f.min("minCheckinCount", st.CheckinCount) && f.max("maxCheckinCount", st.CheckinCount) {
+			statistics = append(statistics, st)
+		}
+	}
+	if f.err != nil {
+		writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, f.err.Error())
+		return
+	}
+	writeJSON(rw, http.StatusOK, statistics)
+}
+
+// statistic computes the statistic of an event from its guests. Companions are counted via entourageCount.
+func (s *Server) statistic(e sweap.Event) sweap.EventStatistic {
+	st := sweap.EventStatistic{ID: e.ID, Version: e.Version, ExternalID: e.ExternalID}
+	if e.CreatedAt != nil {
+		st.CreatedAt = *e.CreatedAt
+	}
+	if e.UpdatedAt != nil {
+		st.UpdatedAt = *e.UpdatedAt
+	}
+
+	for _, g := range s.guests.list() {
+		if g.EventID != e.ID {
+			continue
+		}
+		n := 1 + g.EntourageCount
+		st.GuestCount += n
+		switch g.InvitationState {
+		case sweap.ACCEPTED:
+			st.AcceptedCount += n
+		case sweap.DECLINED:
+			st.DeclindedCount += n
+		case sweap.NO_REPLY:
+			st.NoReplyCount += n
+		}
+		if g.AttendanceState == sweap.PRESENT {
+			st.CheckinCount += n
+		}
+	}
+	return st
+}
+
+// assignFieldIDs gives new custom field definitions an ID, as the API does.
+func assignFieldIDs(defs []sweap.CustomFieldDefinitions) {
+	for i := range defs {
+		if defs[i].ID == "" {
+			defs[i].ID = newID()
+		}
+	}
+}
@@ -0,0 +1,135 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filter matches values against the query parameters of a request.
+// Parameters missing in the query match everything.
+type filter struct {
+	q   url.Values
+	err error
+}
+
+func newFilter(q url.Values) *filter {
+	return &filter{q: q}
+}
+
+func (f *filter) equal(key, v string) bool {
+	p := f.q.Get(key)
+	return p == "" || strings.EqualFold(p, v)
+}
+
+func (f *filter) contains(key, v string) bool {
+	p := f.q.Get(key)
+	return p == "" || strings.Contains(strings.ToLower(v), strings.ToLower(p))
+}
+
+func (f *filter) after(key string, t *time.Time) bool {
+	p := f.q.Get(key)
+	if p == "" {
+		return true
+	}
+	after, err := time.Parse(time.RFC3339, p)
+	if err != nil {
+		f.err = fmt.Errorf("invalid %v: %v", key, err)
+		return false
+	}
+	return t != nil && t.After(after)
+}
+
+func (f *filter) min(key string, v int) bool {
+	p := f.q.Get(key)
+	if p == "" {
+		return true
+	}
+	m, err := strconv.Atoi(p)
+	if err != nil {
+		f.err = fmt.Errorf("invalid %v: %v", key, err)
+		return false
+	}
+	return m < 0 || v >= m
+}
+
+func (f *filter) max(key string, v int) bool {
+	p := f.q.Get(key)
+	if p == "" {
+		return true
+	}
+	m, err := strconv.Atoi(p)
+	if err != nil {
+		f.err = fmt.Errorf("invalid %v: %v", key, err)
+		return false
+	}
+	return m < 0 || v <= m
+}
+
+func (f *filter) intValue(key string, def int) int {
+	v, err := strconv.Atoi(f.q.Get(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// externalID returns the string form of an externalId, which is untyped in the sweap structs.
+func externalID(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// pageable has the layout of the pageable field of sweap.GuestPages and sweap.EventPages.
+type pageable struct {
+	Size          int `json:"size"`
+	TotalElements int `json:"totalElements"`
+	TotalPages    int `json:"totalPages"`
+	Page          int `json:"page"`
+}
+
+// paginate returns the requested page of items. Size defaults to 100.
+func paginate[T any](items []T, page, size int) ([]T, pageable) {
+	if size <= 0 {
+		size = 100
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	p := pageable{Size: size, Page: page, TotalElements: len(items), TotalPages: (len(items) + size - 1) / size}
+	start := page * size
+	if start >= len(items) {
+		return []T{}, p
+	}
+	end := start + size
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end], p
+}
@@ -0,0 +1,124 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"net/http"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+func (s *Server) handleGuests(rw http.ResponseWriter, r *http.Request, path []string) {
+	switch {
+	case len(path) == 0 && r.Method == http.MethodGet:
+		if guests, ok := s.searchGuests(rw, r); ok {
+			writeJSON(rw, http.StatusOK, guests)
+		}
+
+	case len(path) == 1 && path[0] == "paginated" && r.Method == http.MethodGet:
+		guests, ok := s.searchGuests(rw, r)
+		if !ok {
+			return
+		}
+		f := newFilter(r.URL.Query())
+		p := sweap.GuestPages{Status: string(sweap.OK)}
+		p.Content, p.Pageable = paginate(guests, f.intValue("page", 0), f.intValue("size", 100))
+		writeJSON(rw, http.StatusOK, p)
+
+	case len(path) == 0 && r.Method == http.MethodPost:
+		var g sweap.Guest
+		if !decode(rw, r, &g) {
+			return
+		}
+		if g.EventID == "" {
+			writeError(rw, http.StatusBadRequest, sweap.VALIDATION_EXCEPTION, "eventId must not be empty")
+			return
+		}
+		if _, ok := s.events.get(g.EventID); !ok {
+			writeError(rw, http.StatusNotFound, sweap.NOT_FOUND, "event "+g.EventID+" not found")
+			return
+		}
+		g.ID, g.Version, g.CreatedAt, g.UpdatedAt = "", 0, nil, nil
+		writeJSON(rw, http.StatusCreated, s.createGuest(g))
+
+	case len(path) == 1 && r.Method == http.MethodGet:
+		g, ok := s.guests.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		writeJSON(rw, http.StatusOK, g)
+
+	case len(path) == 1 && r.Method == http.MethodPut:
+		old, ok := s.guests.get(path[0])
+		if !ok {
+			notFound(rw, r)
+			return
+		}
+		var g sweap.Guest
+		if !decode(rw, r, &g) {
+			return
+		}
+		g.ID, g.Version, g.CreatedAt, g.UpdatedAt = old.ID, old.Version, old.CreatedAt, old.UpdatedAt
+		g.InvitationID, g.TicketID = old.InvitationID, old.TicketID
+		s.touch(&g.Version, &g.UpdatedAt)
+		s.guests.put(g.ID, g)
+		writeJSON(rw, http.StatusOK, g)
+
+	case len(path) == 1 && r.Method == http.MethodDelete:
+		if !s.guests.delete(path[0]) {
+			notFound(rw, r)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+
+	default:
+		methodNotAllowed(rw, r)
+	}
+}
+
+// searchGuests applies the guest filters of the query. It writes an error and returns false for invalid queries.
+func (s *Server) searchGuests(rw http.ResponseWriter, r *http.Request) (sweap.Guests, bool) {
+	q := r.URL.Query()
+	if q.Get("eventId") == "" && q.Get("id") == "" && q.Get("invitationId") == "" {
+		writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, "one of eventId, id or invitationId is required")
+		return nil, false
+	}
+
+	f := newFilter(q)
+	guests := sweap.Guests{}
+	for _, g := range s.guests.list() {
+		if f.equal("eventId", g.EventID) && f.equal("id", g.ID) && f.equal("invitationId", g.InvitationID) &&
+			f.equal("firstName", g.FirstName) && f.contains("firstNameContains", g.FirstName) &&
+			f.equal("lastName", g.LastName) && f.contains("lastNameContains", g.LastName) &&
+			f.equal("email", g.Email) && f.equal("invitationState", string(g.InvitationState)) &&
+			f.equal("externalId", externalID(g.ExternalID)) && f.equal("ticketId", g.TicketID) &&
+			f.after("createdAfter", g.CreatedAt) && f.after("updatedAfter", g.UpdatedAt) {
+			guests = append(guests, g)
+		}
+	}
+	if f.err != nil {
+		writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, f.err.Error())
+		return nil, false
+	}
+	return guests, true
+}
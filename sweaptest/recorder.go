@@ -0,0 +1,328 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+// Mode selects how a Recorder handles requests.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette only and never touches the network.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the server and records them to the cassette.
+	ModeRecord
+	// ModePassthrough sends requests to the server without recording.
+	ModePassthrough
+)
+
+// Redacted replaces credentials and personal data in recorded interactions.
+const Redacted = sweap.Redacted
+
+// sensitiveHeaders are never written to a cassette.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// ErrNoInteraction is returned in ModeReplay for requests not found in the cassette.
+var ErrNoInteraction = errors.New("sweaptest: no recorded interaction matches request")
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Status     string      `json:"status"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is a http.RoundTripper recording and replaying interactions with the Sweap API.
+// Plug it into a client with Option:
+//
+//	rec, _ := sweaptest.NewRecorder("testdata/guests.json", sweaptest.ModeReplay)
+//	api, _ := sweap.New(id, secret, sweap.OptionUseStagingEnv(), rec.Option())
+//	...
+//	rec.Save()
+//
+// Requests are matched on method, path, query and body. Before interactions are stored,
+// credentials are removed and personal data is masked like in the debug output of a client,
+// see sweap.Redact; use Sanitize to remove further data. Requests are masked the same way
+// before they are matched, so that requests differing only in masked data are not told apart.
+type Recorder struct {
+	Path         string
+	Mode         Mode
+	Transport    http.RoundTripper  // used in ModeRecord and ModePassthrough, defaults to http.DefaultTransport
+	RedactFields []string           // fields masked in URLs and bodies, sweap.DefaultRedactedFields if nil, none if empty
+	Sanitize     func(*Interaction) // optional, called for each interaction before it is stored
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. In ModeReplay the cassette has to exist.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{Path: path, Mode: mode}
+	if mode != ModeReplay {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("reading cassette %v: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// HTTPClient returns a http.Client using the recorder as transport.
+func (r *Recorder) HTTPClient() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Option returns the option plugging the recorder into a sweap.Client.
+func (r *Recorder) Option() sweap.SweapOptions {
+	return sweap.OptionHTTPClient(r.HTTPClient())
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Interaction{}, r.cassette.Interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing unless in ModeRecord.
+func (r *Recorder) Save() error {
+	if r.Mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(r.Path, data, 0o644)
+}
+
+// RoundTrip implements http.RoundTripper. The request is not modified; in ModeRecord
+// a copy of it is sent.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.Mode == ModePassthrough {
+		return r.transport().RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := r.sanitizeRequest(RecordedRequest{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: string(body)})
+
+	if r.Mode == ModeReplay {
+		return r.replay(req, recorded)
+	}
+
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.transport().RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	i := Interaction{
+		Request:  recorded,
+		Response: r.sanitizeResponse(RecordedResponse{StatusCode: resp.StatusCode, Status: resp.Status, Header: resp.Header.Clone(), Body: string(respBody)}),
+	}
+	if r.Sanitize != nil {
+		r.Sanitize(&i)
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, i)
+	r.used = append(r.used, true)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+func (r *Recorder) transport() http.RoundTripper {
+	if r.Transport != nil {
+		return r.Transport
+	}
+	return http.DefaultTransport
+}
+
+// replay answers with the first unused matching interaction. Once all matching
+// interactions are used, the last one is repeated.
+func (r *Recorder) replay(req *http.Request, recorded RecordedRequest) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, in := range r.cassette.Interactions {
+		if !matches(in.Request, recorded) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %v %v", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+	r.used[found] = true
+
+	rr := r.cassette.Interactions[found].Response
+	return &http.Response{
+		StatusCode:    rr.StatusCode,
+		Status:        rr.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rr.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(rr.Body))),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}, nil
+}
+
+// matches compares method, path, query and body of two requests. The host is ignored,
+// so that cassettes recorded against one environment can be replayed against a fake URL.
+func matches(a, b RecordedRequest) bool {
+	if a.Method != b.Method {
+		return false
+	}
+
+	ua, errA := url.Parse(a.URL)
+	ub, errB := url.Parse(b.URL)
+	if errA != nil || errB != nil {
+		return a.URL == b.URL && a.Body == b.Body
+	}
+	if ua.Path != ub.Path || !reflect.DeepEqual(ua.Query(), ub.Query()) {
+		return false
+	}
+	return sameBody(a.Body, b.Body)
+}
+
+// sameBody compares JSON bodies by value and other bodies byte by byte.
+func sameBody(a, b string) bool {
+	if a == b {
+		return true
+	}
+	var ja, jb interface{}
+	if json.Unmarshal([]byte(a), &ja) != nil || json.Unmarshal([]byte(b), &jb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(ja, jb)
+}
+
+// readRequestBody reads and closes the body of req. req.GetBody is preferred, so that
+// the body of req is left as it is where possible.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	rc := req.Body
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		rc = body
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (r *Recorder) redact(s string) string {
+	fields := r.RedactFields
+	if fields == nil {
+		fields = sweap.DefaultRedactedFields
+	}
+	return sweap.Redact(s, fields...)
+}
+
+func (r *Recorder) sanitizeRequest(rr RecordedRequest) RecordedRequest {
+	for _, h := range sensitiveHeaders {
+		rr.Header.Del(h)
+	}
+	rr.URL = r.redact(rr.URL)
+	rr.Body = r.redact(rr.Body)
+	return rr
+}
+
+func (r *Recorder) sanitizeResponse(rr RecordedResponse) RecordedResponse {
+	for _, h := range sensitiveHeaders {
+		rr.Header.Del(h)
+	}
+	rr.Body = r.redact(rr.Body)
+	return rr
+}
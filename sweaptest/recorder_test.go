@@ -0,0 +1,124 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	srv, _ := newServer(t)
+	cassette := filepath.Join(t.TempDir(), "cassettes", "guests.json")
+
+	rec, err := sweaptest.NewRecorder(cassette, sweaptest.ModeRecord)
+	assert.Nil(t, err)
+	api, err := sweap.New("id", "very-secret", append(srv.Options(), rec.Option())...)
+	assert.Nil(t, err)
+
+	guests, err := api.GetGuests(eventID)
+	assert.Nil(t, err)
+	created, err := api.CreateGuest(sweap.Guest{EventID: eventID, FirstName: "Theo", LastName: "Vassiliou"})
+	assert.Nil(t, err)
+	assert.Nil(t, rec.Save())
+
+	data, err := os.ReadFile(cassette)
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(string(data), "very-secret"))
+	assert.False(t, strings.Contains(string(data), sweaptest.AccessToken))
+	assert.False(t, strings.Contains(string(data), (*guests)[0].FirstName), "personal data is masked")
+	assert.False(t, strings.Contains(string(data), "Vassiliou"))
+
+	// replay without a server
+	url := srv.APIURL()
+	srv.Close()
+
+	replay, err := sweaptest.NewRecorder(cassette, sweaptest.ModeReplay)
+	assert.Nil(t, err)
+	api, err = sweap.New("id", "very-secret", sweap.OptionAPIURL(url), sweap.OptionTOKENURL(srv.TokenURL()), replay.Option())
+	assert.Nil(t, err)
+
+	replayed, err := api.GetGuests(eventID)
+	assert.Nil(t, err)
+	assert.Len(t, *replayed, len(*guests))
+	assert.Equal(t, (*guests)[0].ID, (*replayed)[0].ID)
+	assert.Equal(t, sweaptest.Redacted, (*replayed)[0].FirstName)
+
+	replayedGuest, err := api.CreateGuest(sweap.Guest{LastName: "Vassiliou", FirstName: "Theo", EventID: eventID})
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID, replayedGuest.ID)
+
+	_, err = api.GetGuests("other-event")
+	assert.True(t, errors.Is(err, sweaptest.ErrNoInteraction))
+}
+
+func TestRecordUnmasked(t *testing.T) {
+	srv, _ := newServer(t)
+	cassette := filepath.Join(t.TempDir(), "guests.json")
+
+	rec, err := sweaptest.NewRecorder(cassette, sweaptest.ModeRecord)
+	assert.Nil(t, err)
+	rec.RedactFields = []string{}
+	api, err := sweap.New("id", "very-secret", append(srv.Options(), rec.Option())...)
+	assert.Nil(t, err)
+
+	guests, err := api.GetGuests(eventID)
+	assert.Nil(t, err)
+	assert.Nil(t, rec.Save())
+
+	data, err := os.ReadFile(cassette)
+	assert.Nil(t, err)
+	assert.True(t, strings.Contains(string(data), (*guests)[0].FirstName))
+	assert.False(t, strings.Contains(string(data), "very-secret"))
+}
+
+func TestRecorderKeepsRequest(t *testing.T) {
+	srv, _ := newServer(t)
+	rec, _ := sweaptest.NewRecorder(filepath.Join(t.TempDir(), "guests.json"), sweaptest.ModeRecord)
+
+	body := io.NopCloser(strings.NewReader(`{"eventId":"` + eventID + `","firstName":"Theo"}`))
+	req, _ := http.NewRequest(http.MethodPost, srv.APIURL()+"guests", body)
+	req.Header.Set("Authorization", "Bearer "+sweaptest.AccessToken)
+	resp, err := rec.RoundTrip(req)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Same(t, req, resp.Request)
+	assert.True(t, req.Body == body, "the body of the request is not replaced")
+	assert.Equal(t, "Bearer "+sweaptest.AccessToken, req.Header.Get("Authorization"))
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	_, err := sweaptest.NewRecorder(filepath.Join(t.TempDir(), "missing.json"), sweaptest.ModeReplay)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,460 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+// Package sweaptest provides an in-memory fake of the Sweap API for offline testing.
+//
+// A Server is seeded from Go structs and is used by pointing a sweap.Client at it:
+//
+//	srv := sweaptest.NewServer(sweaptest.Seed{Events: sweap.Events{{ID: "e1", Name: "Test"}}})
+//	defer srv.Close()
+//	api, _ := srv.Client()
+package sweaptest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+// AccessToken is the bearer token issued by the fake token endpoint.
+const AccessToken = "sweaptest-access-token"
+
+// Seed holds the initial data of a Server. Entries without ID get one assigned.
+type Seed struct {
+	Events      sweap.Events
+	Guests      sweap.Guests
+	Categories  sweap.Categories
+	BulkImports sweap.GuestBulkImports
+}
+
+// Server is a fake Sweap API backed by memory.
+type Server struct {
+	*httptest.Server
+
+	// ClientID and ClientSecret, if set, are the only credentials accepted by the token endpoint.
+	ClientID     string
+	ClientSecret string
+	// ImportPolls is the number of state requests a bulk import stays in IMPORT_STARTED.
+	ImportPolls int
+	// Now returns the time used for createdAt and updatedAt, defaults to time.Now.
+	Now func() time.Time
+
+	mu          sync.Mutex
+	events      table[sweap.Event]
+	guests      table[sweap.Guest]
+	categories  table[sweap.Category]
+	bulkImports table[bulkImport]
+	requests    []Request
+}
+
+// Request is an API request received by the Server.
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+}
+
+type bulkImport struct {
+	sweap.GuestBulkImport
+	polls int
+}
+
+// NewServer starts a fake Sweap API seeded with the given data.
+// The caller should call Close when finished, to shut it down.
+func NewServer(seed Seed) *Server {
+	s := &Server{
+		ImportPolls: 1,
+		Now:         time.Now,
+		events:      newTable[sweap.Event](),
+		guests:      newTable[sweap.Guest](),
+		categories:  newTable[sweap.Category](),
+		bulkImports: newTable[bulkImport](),
+	}
+
+	for _, e := range seed.Events {
+		s.AddEvent(e)
+	}
+	for _, g := range seed.Guests {
+		s.AddGuest(g)
+	}
+	for _, c := range seed.Categories {
+		s.AddCategory(c)
+	}
+	for _, gbi := range seed.BulkImports {
+		s.AddBulkImport(gbi)
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// APIURL returns the URL to be used with sweap.OptionAPIURL.
+func (s *Server) APIURL() string {
+	return s.URL + "/"
+}
+
+// TokenURL returns the URL to be used with sweap.OptionTOKENURL.
+func (s *Server) TokenURL() string {
+	return s.URL + "/token"
+}
+
+// Options returns the options pointing a sweap.Client to this server.
+func (s *Server) Options() []sweap.SweapOptions {
+	return []sweap.SweapOptions{sweap.OptionAPIURL(s.APIURL()), sweap.OptionTOKENURL(s.TokenURL())}
+}
+
+// Client returns a sweap.Client talking to this server. Additional options are applied after the server options.
+func (s *Server) Client(options ...sweap.SweapOptions) (*sweap.Client, error) {
+	return sweap.New(s.ClientID, s.ClientSecret, append(s.Options(), options...)...)
+}
+
+// AddEvent stores an event and returns the stored copy.
+func (s *Server) AddEvent(e sweap.Event) sweap.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stamp(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+	s.events.put(e.ID, e)
+	return e
+}
+
+// AddGuest stores a guest and returns the stored copy.
+func (s *Server) AddGuest(g sweap.Guest) sweap.Guest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createGuest(g)
+}
+
+// AddCategory stores a category and returns the stored copy.
+func (s *Server) AddCategory(c sweap.Category) sweap.Category {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if c.ID == "" {
+		c.ID = newID()
+	}
+	s.categories.put(c.ID, c)
+	return c
+}
+
+// AddBulkImport stores a guest bulk import and returns the stored copy.
+func (s *Server) AddBulkImport(gbi sweap.GuestBulkImport) sweap.GuestBulkImport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.createBulkImport(gbi)
+}
+
+// Event returns the stored event with the given ID.
+func (s *Server) Event(id string) (sweap.Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.events.get(id)
+}
+
+// Guest returns the stored guest with the given ID.
+func (s *Server) Guest(id string) (sweap.Guest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.guests.get(id)
+}
+
+// Guests returns all stored guests of an event in creation order.
+func (s *Server) Guests(eventID string) sweap.Guests {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	guests := sweap.Guests{}
+	for _, g := range s.guests.list() {
+		if g.EventID == eventID {
+			guests = append(guests, g)
+		}
+	}
+	return guests
+}
+
+// Categories returns all stored categories of an event in creation order.
+func (s *Server) Categories(eventID string) sweap.Categories {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	categories := sweap.Categories{}
+	for _, c := range s.categories.list() {
+		if c.EventID == eventID {
+			categories = append(categories, c)
+		}
+	}
+	return categories
+}
+
+// BulkImport returns the stored guest bulk import with the given ID.
+func (s *Server) BulkImport(id string) (sweap.GuestBulkImport, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	gbi, ok := s.bulkImports.get(id)
+	return gbi.GuestBulkImport, ok
+}
+
+// UpdateGuest changes a stored guest as if it was edited by somebody else.
+// Version and updatedAt are advanced.
+func (s *Server) UpdateGuest(id string, f func(*sweap.Guest)) (sweap.Guest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.guests.get(id)
+	if !ok {
+		return g, false
+	}
+	f(&g)
+	s.touch(&g.Version, &g.UpdatedAt)
+	s.guests.put(id, g)
+	return g, true
+}
+
+// DeleteGuest removes a stored guest.
+func (s *Server) DeleteGuest(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.guests.delete(id)
+}
+
+// Requests returns the API requests received so far, excluding token requests.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+// ----- bookkeeping -------
+
+// stamp assigns an ID and creation timestamps if missing. s.mu must be held.
+func (s *Server) stamp(id *string, createdAt, updatedAt **time.Time) {
+	if *id == "" {
+		*id = newID()
+	}
+	now := s.Now().UTC()
+	if *createdAt == nil {
+		*createdAt = &now
+	}
+	if *updatedAt == nil {
+		*updatedAt = &now
+	}
+}
+
+// touch advances version and updatedAt. s.mu must be held.
+func (s *Server) touch(version *int, updatedAt **time.Time) {
+	now := s.Now().UTC()
+	*version++
+	*updatedAt = &now
+}
+
+func (s *Server) createGuest(g sweap.Guest) sweap.Guest {
+	s.stamp(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+	if g.InvitationID == "" {
+		g.InvitationID = strings.ReplaceAll(newID(), "-", "")
+	}
+	if g.TicketID == "" {
+		g.TicketID = strings.ToUpper(strings.ReplaceAll(newID(), "-", "")[:12])
+	}
+	if g.InvitationState == "" {
+		g.InvitationState = sweap.NONE
+	}
+	if g.AttendanceState == "" {
+		g.AttendanceState = sweap.NONEATTENDANCE
+	}
+	if g.CustomFields == nil {
+		g.CustomFields = sweap.CustomFields{}
+	}
+	s.guests.put(g.ID, g)
+	return g
+}
+
+func (s *Server) createBulkImport(gbi sweap.GuestBulkImport) sweap.GuestBulkImport {
+	s.stamp(&gbi.ID, &gbi.CreatedAt, &gbi.UpdatedAt)
+	if gbi.State == nil {
+		state := sweap.UPLOADSTARTED
+		gbi.State = &state
+	}
+	if gbi.Guests == nil {
+		gbi.Guests = &sweap.Guests{}
+	}
+	s.bulkImports.put(gbi.ID, bulkImport{GuestBulkImport: gbi})
+	return gbi
+}
+
+// ----- HTTP -------
+
+func (s *Server) serveHTTP(rw http.ResponseWriter, r *http.Request) {
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	if path[0] == "token" {
+		s.handleToken(rw, r)
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer "+AccessToken {
+		writeError(rw, http.StatusUnauthorized, sweap.UNAUTHORIZED, "missing or invalid access token")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query()})
+
+	switch path[0] {
+	case "events":
+		s.handleEvents(rw, r, path[1:])
+	case "guests":
+		s.handleGuests(rw, r, path[1:])
+	case "categories":
+		s.handleCategories(rw, r, path[1:])
+	case "event-statistics":
+		s.handleEventStatistics(rw, r, path[1:])
+	case "guest-bulk-imports":
+		s.handleBulkImports(rw, r, path[1:])
+	case "management":
+		if len(path) == 2 && path[1] == "check-credentials" && r.Method == http.MethodGet {
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+		notFound(rw, r)
+	default:
+		notFound(rw, r)
+	}
+}
+
+func (s *Server) handleToken(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(rw, r)
+		return
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostFormValue("client_id"), r.PostFormValue("client_secret")
+	}
+	if (s.ClientID != "" && id != s.ClientID) || (s.ClientSecret != "" && secret != s.ClientSecret) {
+		writeJSON(rw, http.StatusUnauthorized, map[string]string{"error": "unauthorized_client"})
+		return
+	}
+
+	writeJSON(rw, http.StatusOK, map[string]interface{}{
+		"access_token": AccessToken,
+		"expires_in":   300,
+		"token_type":   "Bearer",
+		"scope":        "profile email",
+	})
+}
+
+func writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	json.NewEncoder(rw).Encode(v)
+}
+
+// writeError answers in the format of the Sweap API, e.g. 404 becomes code 4040.
+func writeError(rw http.ResponseWriter, code int, status sweap.Status, message string) {
+	writeJSON(rw, code, sweap.SweapError{Error_: string(status), Code: code * 10, Message: message})
+}
+
+func notFound(rw http.ResponseWriter, r *http.Request) {
+	writeError(rw, http.StatusNotFound, sweap.NOT_FOUND, fmt.Sprintf("%v not found", r.URL.Path))
+}
+
+func methodNotAllowed(rw http.ResponseWriter, r *http.Request) {
+	writeError(rw, http.StatusMethodNotAllowed, sweap.BAD_REQUEST, fmt.Sprintf("%v not supported on %v", r.Method, r.URL.Path))
+}
+
+func decode(rw http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(rw, http.StatusBadRequest, sweap.BAD_REQUEST, err.Error())
+		return false
+	}
+	return true
+}
+
+// newID returns a random UUID.
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// table keeps rows in creation order.
+type table[T any] struct {
+	ids  []string
+	rows map[string]T
+}
+
+func newTable[T any]() table[T] {
+	return table[T]{rows: make(map[string]T)}
+}
+
+func (t *table[T]) get(id string) (T, bool) {
+	v, ok := t.rows[id]
+	return v, ok
+}
+
+func (t *table[T]) put(id string, v T) {
+	if _, ok := t.rows[id]; !ok {
+		t.ids = append(t.ids, id)
+	}
+	t.rows[id] = v
+}
+
+func (t *table[T]) delete(id string) bool {
+	if _, ok := t.rows[id]; !ok {
+		return false
+	}
+	delete(t.rows, id)
+	for i, v := range t.ids {
+		if v == id {
+			t.ids = append(t.ids[:i], t.ids[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (t *table[T]) list() []T {
+	l := make([]T, 0, len(t.ids))
+	for _, id := range t.ids {
+		l = append(l, t.rows[id])
+	}
+	return l
+}
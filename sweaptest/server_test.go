@@ -0,0 +1,235 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweaptest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+const eventID = "9a96ba92-46b4-4e41-bcc0-fb273dbf22b7"
+
+func newServer(t *testing.T) (*sweaptest.Server, *sweap.Client) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{
+			{ID: eventID, Name: "Retro Ownership", State: string(sweap.DRAFT)},
+			{ID: "c00a3c96-c35c-44f7-b5fd-d8526e8fdd9b", Name: "Summer Reception 2022", State: string(sweap.ACTIVE)},
+		},
+		Guests: sweap.Guests{
+			{EventID: eventID, FirstName: "Sven", LastName: "Frauen", InvitationState: sweap.ACCEPTED, EntourageCount: 1},
+			{EventID: eventID, FirstName: "Wissam", LastName: "Ghozlan", InvitationState: sweap.DECLINED},
+			{EventID: eventID, FirstName: "Matthias", LastName: "Heicke", AttendanceState: sweap.PRESENT, InvitationState: sweap.ACCEPTED},
+		},
+		Categories: sweap.Categories{
+			{Name: "VIP", EventID: eventID, ColorHex: "#ff0000"},
+		},
+	})
+	t.Cleanup(srv.Close)
+
+	api, err := srv.Client()
+	assert.Nil(t, err)
+	return srv, api
+}
+
+func TestEvents(t *testing.T) {
+	_, api := newServer(t)
+
+	events, err := api.GetEvents()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(*events))
+
+	events, err = api.SearchEvents(sweap.EventSearchParameter{NameContains: "summer"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*events))
+	assert.Equal(t, "Summer Reception 2022", (*events)[0].Name)
+
+	event, err := api.GetEventById(eventID)
+	assert.Nil(t, err)
+	assert.Equal(t, "Retro Ownership", event.Name)
+
+	_, err = api.GetEventById("CANTFIND")
+	assert.True(t, errors.Is(err, sweap.ErrNotFound))
+}
+
+func TestGuests(t *testing.T) {
+	srv, api := newServer(t)
+
+	guests, err := api.GetGuests(eventID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(*guests))
+
+	created, err := api.CreateGuest(sweap.Guest{EventID: eventID, FirstName: "Theo", LastName: "Vassiliou", Email: "theo@example.com"})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", created.ID)
+	assert.NotEqual(t, "", created.TicketID)
+	assert.Equal(t, 4, len(srv.Guests(eventID)))
+
+	created.LastName = "Vassiliou-Gioles"
+	updated, err := api.UpdateGuest(*created)
+	assert.Nil(t, err)
+	assert.Equal(t, "Vassiliou-Gioles", updated.LastName)
+	assert.Equal(t, created.Version+1, updated.Version)
+
+	found, err := api.SearchGuests(eventID, sweap.GuestSearchParameter{Email: "THEO@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*found))
+
+	assert.Nil(t, api.DeleteGuest(created.ID))
+	_, err = api.GetGuestById(created.ID)
+	assert.True(t, errors.Is(err, sweap.ErrNotFound))
+	assert.Equal(t, 4040, err.(*sweap.SweapError).Code)
+
+	_, err = api.CreateGuest(sweap.Guest{EventID: "unknown"})
+	assert.True(t, errors.Is(err, sweap.ErrNotFound))
+}
+
+func TestGuestsPaginated(t *testing.T) {
+	_, api := newServer(t)
+
+	page, err := api.GetGuestsPaginated(eventID, sweap.PaginationParameter{Size: 2, Page: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(page.Content))
+	assert.Equal(t, "Matthias", page.Content[0].FirstName)
+	assert.Equal(t, 3, page.Pageable.TotalElements)
+	assert.Equal(t, 2, page.Pageable.TotalPages)
+}
+
+func TestUpdatedAfter(t *testing.T) {
+	srv, api := newServer(t)
+	guests := srv.Guests(eventID)
+
+	later := time.Now().Add(time.Hour)
+	srv.Now = func() time.Time { return later }
+	srv.UpdateGuest(guests[1].ID, func(g *sweap.Guest) { g.Comment = "changed" })
+
+	after := later.Add(-time.Minute)
+	found, err := api.GetGuestsContext(context.Background(), eventID, sweap.GuestSearchParameter{UpdatedAfter: &after})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*found))
+	assert.Equal(t, guests[1].ID, (*found)[0].ID)
+}
+
+func TestCategories(t *testing.T) {
+	srv, api := newServer(t)
+
+	categories, err := api.GetCategories(eventID)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*categories))
+
+	category, err := api.GetCategoryById(srv.Categories(eventID)[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, "VIP", category.Name)
+}
+
+func TestEventStatistics(t *testing.T) {
+	_, api := newServer(t)
+
+	statistics, err := api.SearchEventStatistics(sweap.EventStatisticsSearchParameter{
+		Id: eventID, MinGuestCount: -1, MaxGuestCount: -1, MinAcceptedCount: -1, MaxAcceptedCount: -1,
+		MinDeclinedCount: -1, MaxDeclinedCount: -1, MinNoReplyCount: -1, MaxNoReplyCount: -1, MinCheckinCount: -1, MaxCheckinCount: -1,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*statistics))
+	st := (*statistics)[0]
+	assert.Equal(t, 4, st.GuestCount)
+	assert.Equal(t, 3, st.AcceptedCount)
+	assert.Equal(t, 1, st.DeclindedCount)
+	assert.Equal(t, 1, st.CheckinCount)
+
+	statistics, err = api.GetEventStatistics()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(*statistics))
+}
+
+func TestBulkImport(t *testing.T) {
+	srv, api := newServer(t)
+	srv.ImportPolls = 2
+
+	gbi, err := api.CreateGuestBulkImportObject(sweap.GuestBulkImport{Name: "import", EventId: eventID, ExternalID: "run-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.UPLOADSTARTED, *gbi.State)
+
+	assert.Nil(t, api.BulkImportUpdateBatch(gbi.ID, sweap.Guests{{FirstName: "A", LastName: "One"}, {FirstName: "B", LastName: "Two"}}))
+	assert.Nil(t, api.BulkImportUpdateBatch(gbi.ID, sweap.Guests{{FirstName: "C", LastName: "Three"}}))
+	assert.Nil(t, api.BulkImportFinishUpload(gbi.ID))
+	assert.NotNil(t, api.BulkImportUpdateBatch(gbi.ID, sweap.Guests{{FirstName: "D"}}))
+
+	states := []sweap.GuestBulkImportStatus{}
+	for i := 0; i < 4; i++ {
+		state, err := api.GetSpecificBulkImportState(gbi.ID)
+		assert.Nil(t, err)
+		states = append(states, state.State)
+	}
+	assert.Equal(t, []sweap.GuestBulkImportStatus{sweap.IMPORTSTARTED, sweap.IMPORTSTARTED, sweap.IMPORTFINISHED, sweap.IMPORTFINISHED}, states)
+	assert.Equal(t, 6, len(srv.Guests(eventID)))
+
+	imports, err := api.GetAllBulkImportsContext(context.Background(), sweap.GuestBulkImportSearchParameter{ExternalID: "run-1"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(*imports))
+}
+
+func TestCredentials(t *testing.T) {
+	srv, _ := newServer(t)
+	srv.ClientID, srv.ClientSecret = "id", "secret"
+
+	api, err := srv.Client()
+	assert.Nil(t, err)
+	ok, err := api.CheckCredentials()
+	assert.True(t, ok)
+	assert.Nil(t, err)
+
+	wrong, err := sweap.New("id", "wrong", srv.Options()...)
+	assert.Nil(t, err)
+	_, err = wrong.GetEvents()
+	assert.NotNil(t, err)
+}
+
+func TestIterateGuests(t *testing.T) {
+	_, api := newServer(t)
+
+	it := api.IterateGuests(context.Background(), eventID, sweap.NewGuestSearchParameters(), sweap.IteratorPageSize(2), sweap.IteratorPrefetch(true))
+	names := []string{}
+	for it.Next() {
+		names = append(names, it.Value().FirstName)
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, []string{"Sven", "Wissam", "Matthias"}, names)
+}
+
+func TestIterateEvents(t *testing.T) {
+	_, api := newServer(t)
+
+	it := api.IterateEvents(context.Background(), sweap.NewEventSearchParameters(), sweap.IteratorPageSize(1))
+	count := 0
+	for it.Next() {
+		count++
+	}
+	assert.Nil(t, it.Err())
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,292 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// UpsertKey is a field identifying an existing guest when upserting.
+type UpsertKey string
+
+const (
+	EXTERNALIDKEY UpsertKey = "externalId"
+	EMAILKEY      UpsertKey = "email"
+)
+
+type UpsertOutcome string
+
+const (
+	CREATED   UpsertOutcome = "CREATED"
+	UPDATED   UpsertOutcome = "UPDATED"
+	UNCHANGED UpsertOutcome = "UNCHANGED"
+	FAILED    UpsertOutcome = "FAILED"
+)
+
+// UpsertResult is the outcome of upserting a single guest.
+// Guest is the stored guest, or the given one if the upsert failed with Err.
+type UpsertResult struct {
+	Guest   Guest
+	Outcome UpsertOutcome
+	Err     error
+}
+
+type UpsertOptions func(*upsertConfig)
+
+type upsertConfig struct {
+	keys []UpsertKey
+}
+
+// UpsertKeys sets the fields used, in order, to find the existing guest. Defaults to ExternalID, then Email.
+func UpsertKeys(keys ...UpsertKey) func(*upsertConfig) {
+	return func(c *upsertConfig) {
+		c.keys = keys
+	}
+}
+
+func newUpsertConfig(options []UpsertOptions) upsertConfig {
+	c := upsertConfig{keys: []UpsertKey{EXTERNALIDKEY, EMAILKEY}}
+	for _, opt := range options {
+		opt(&c)
+	}
+	return c
+}
+
+// UpsertGuest creates the guest or updates the existing guest of the event with the same
+// ExternalID or, if not found, Email. Updates are skipped if nothing would change.
+func (api *Client) UpsertGuest(g Guest, options ...UpsertOptions) (*UpsertResult, error) {
+	return api.UpsertGuestContext(context.Background(), g, options...)
+}
+
+// UpsertGuestContext creates or updates a guest with a custom context, see UpsertGuest.
+// Fields of g that are empty (CustomFields, CategoryID, ParentGuestID, InvitationState, AttendanceState)
+// keep the value of the existing guest.
+func (api *Client) UpsertGuestContext(ctx context.Context, g Guest, options ...UpsertOptions) (*UpsertResult, error) {
+	c := newUpsertConfig(options)
+	r := api.upsert(ctx, g, c, func(key UpsertKey, value string) (Guests, error) {
+		params := NewGuestSearchParameters()
+		switch key {
+		case EXTERNALIDKEY:
+			params.ExternalID = value
+		case EMAILKEY:
+			params.Email = value
+		}
+		found, err := api.GetGuestsContext(ctx, g.EventID, params)
+		if err != nil {
+			return nil, err
+		}
+		return *found, nil
+	})
+	return &r, r.Err
+}
+
+// UpsertGuests upserts the guests one after the other, see UpsertGuest, and returns a result per guest.
+// Instead of searching each guest, all guests of the involved events are read once.
+func (api *Client) UpsertGuests(guests Guests, options ...UpsertOptions) []UpsertResult {
+	return api.UpsertGuestsContext(context.Background(), guests, options...)
+}
+
+// UpsertGuestsContext upserts the guests with a custom context, see UpsertGuests.
+func (api *Client) UpsertGuestsContext(ctx context.Context, guests Guests, options ...UpsertOptions) []UpsertResult {
+	c := newUpsertConfig(options)
+	indexes := map[string]*guestIndex{}
+	results := make([]UpsertResult, len(guests))
+
+	for i, g := range guests {
+		idx, ok := indexes[g.EventID]
+		if !ok && g.EventID != "" {
+			idx = &guestIndex{}
+			idx.err = idx.load(ctx, api, g.EventID, c.keys)
+			indexes[g.EventID] = idx
+		}
+		results[i] = api.upsert(ctx, g, c, func(key UpsertKey, value string) (Guests, error) {
+			if idx.err != nil {
+				return nil, idx.err
+			}
+			return idx.lookup(key, value), nil
+		})
+		if idx != nil && results[i].Outcome != FAILED {
+			idx.add(results[i].Guest, c.keys)
+		}
+	}
+	return results
+}
+
+type lookupFunc func(key UpsertKey, value string) (Guests, error)
+
+func (api *Client) upsert(ctx context.Context, g Guest, c upsertConfig, lookup lookupFunc) UpsertResult {
+	failed := func(err error) UpsertResult {
+		return UpsertResult{Guest: g, Outcome: FAILED, Err: err}
+	}
+	if g.EventID == "" {
+		return failed(SweapLibraryError{fmt.Sprintf("no event ID given in Guest %v", g)})
+	}
+
+	var existing *Guest
+	for _, key := range c.keys {
+		value := upsertKeyValue(g, key)
+		if value == "" {
+			continue
+		}
+		found, err := lookup(key, value)
+		if err != nil {
+			return failed(err)
+		}
+		if len(found) > 1 {
+			return failed(SweapLibraryError{fmt.Sprintf("%d guests with %v %v", len(found), key, value)})
+		}
+		if len(found) == 1 {
+			existing = &found[0]
+			break
+		}
+	}
+
+	if existing == nil {
+		g.ID = ""
+		created, err := api.CreateGuestContext(ctx, g)
+		if err != nil {
+			return failed(err)
+		}
+		return UpsertResult{Guest: *created, Outcome: CREATED}
+	}
+
+	merged := mergeGuest(*existing, g)
+	if sameGuest(*existing, merged) {
+		return UpsertResult{Guest: *existing, Outcome: UNCHANGED}
+	}
+	updated, err := api.UpdateGuestContext(ctx, merged)
+	if err != nil {
+		return failed(err)
+	}
+	return UpsertResult{Guest: *updated, Outcome: UPDATED}
+}
+
+func upsertKeyValue(g Guest, key UpsertKey) string {
+	switch key {
+	case EXTERNALIDKEY:
+		return externalIDString(g.ExternalID)
+	case EMAILKEY:
+		return strings.ToLower(g.Email)
+	}
+	return ""
+}
+
+// externalIDString returns the string form of an ExternalID, which is a number or a string.
+func externalIDString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// mergeGuest returns existing with the fields set by desired.
+func mergeGuest(existing, desired Guest) Guest {
+	m := desired
+	m.ID, m.Version, m.CreatedAt, m.UpdatedAt = existing.ID, existing.Version, existing.CreatedAt, existing.UpdatedAt
+	m.EventID, m.InvitationID, m.TicketID = existing.EventID, existing.InvitationID, existing.TicketID
+	if m.ExternalID == nil {
+		m.ExternalID = existing.ExternalID
+	}
+	if m.CustomFields == nil {
+		m.CustomFields = existing.CustomFields
+	}
+	if m.CategoryID == "" {
+		m.CategoryID = existing.CategoryID
+	}
+	if m.ParentGuestID == "" {
+		m.ParentGuestID = existing.ParentGuestID
+	}
+	if m.InvitationState == "" {
+		m.InvitationState = existing.InvitationState
+	}
+	if m.AttendanceState == "" {
+		m.AttendanceState = existing.AttendanceState
+	}
+	return m
+}
+
+func sameGuest(a, b Guest) bool {
+	a.ExternalID, b.ExternalID = externalIDString(a.ExternalID), externalIDString(b.ExternalID)
+	ja, _ := json.Marshal(a)
+	jb, _ := json.Marshal(b)
+	return string(ja) == string(jb)
+}
+
+// guestIndex holds the guests of an event by their upsert keys.
+type guestIndex struct {
+	byKey map[UpsertKey]map[string]Guests
+	keyed map[string]map[UpsertKey]string // key values of each indexed guest ID
+	err   error
+}
+
+func (idx *guestIndex) load(ctx context.Context, api *Client, eventID string, keys []UpsertKey) error {
+	idx.byKey = map[UpsertKey]map[string]Guests{}
+	idx.keyed = map[string]map[UpsertKey]string{}
+	it := api.IterateGuests(ctx, eventID, NewGuestSearchParameters())
+	for it.Next() {
+		idx.add(it.Value(), keys)
+	}
+	return it.Err()
+}
+
+// add indexes g, replacing an earlier version of the same guest.
+func (idx *guestIndex) add(g Guest, keys []UpsertKey) {
+	idx.remove(g.ID)
+	idx.keyed[g.ID] = map[UpsertKey]string{}
+	for _, key := range keys {
+		value := upsertKeyValue(g, key)
+		if value == "" {
+			continue
+		}
+		if idx.byKey[key] == nil {
+			idx.byKey[key] = map[string]Guests{}
+		}
+		idx.byKey[key][value] = append(idx.byKey[key][value], g)
+		idx.keyed[g.ID][key] = value
+	}
+}
+
+func (idx *guestIndex) remove(id string) {
+	for key, value := range idx.keyed[id] {
+		found := idx.byKey[key][value]
+		for i := range found {
+			if found[i].ID == id {
+				idx.byKey[key][value] = append(found[:i:i], found[i+1:]...)
+				break
+			}
+		}
+	}
+	delete(idx.keyed, id)
+}
+
+func (idx *guestIndex) lookup(key UpsertKey, value string) Guests {
+	return idx.byKey[key][value]
+}
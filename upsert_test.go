@@ -0,0 +1,121 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func upsertSeed() sweaptest.Seed {
+	return sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Upsert"}},
+		Guests: sweap.Guests{
+			{ID: "g-1", EventID: "event-1", ExternalID: "crm-1", FirstName: "Anna", Email: "anna@example.com",
+				CustomFields: sweap.CustomFields{"title": "Dr."}, InvitationState: sweap.ACCEPTED},
+			{ID: "g-2", EventID: "event-1", FirstName: "Ben", Email: "Ben@Example.com"},
+			{ID: "g-3", EventID: "event-1", FirstName: "Carl", Email: "twins@example.com"},
+			{ID: "g-4", EventID: "event-1", FirstName: "Cleo", Email: "twins@example.com"},
+		},
+	}
+}
+
+func TestUpsertGuest(t *testing.T) {
+	srv := sweaptest.NewServer(upsertSeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	// unchanged: empty fields keep the stored values
+	r, err := api.UpsertGuest(sweap.Guest{EventID: "event-1", ExternalID: "crm-1", FirstName: "Anna", Email: "anna@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.UNCHANGED, r.Outcome)
+	assert.Equal(t, "g-1", r.Guest.ID)
+
+	r, err = api.UpsertGuest(sweap.Guest{EventID: "event-1", ExternalID: "crm-1", FirstName: "Anna", LastName: "Adams", Email: "anna@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.UPDATED, r.Outcome)
+	stored, _ := srv.Guest("g-1")
+	assert.Equal(t, "Adams", stored.LastName)
+	assert.Equal(t, "Dr.", stored.CustomFields["title"])
+	assert.Equal(t, sweap.ACCEPTED, stored.InvitationState)
+
+	// email fallback, case insensitive, adopting the external ID
+	r, err = api.UpsertGuest(sweap.Guest{EventID: "event-1", ExternalID: 42.0, FirstName: "Ben", Email: "ben@example.com"})
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.UPDATED, r.Outcome)
+	assert.Equal(t, "g-2", r.Guest.ID)
+
+	r, err = api.UpsertGuest(sweap.Guest{EventID: "event-1", ExternalID: "crm-9", FirstName: "Dora"})
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.CREATED, r.Outcome)
+	assert.Len(t, srv.Guests("event-1"), 5)
+
+	r, err = api.UpsertGuest(sweap.Guest{EventID: "event-1", Email: "twins@example.com"})
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+	assert.Equal(t, sweap.FAILED, r.Outcome)
+
+	// without the email fallback the guest is created
+	r, err = api.UpsertGuest(sweap.Guest{EventID: "event-1", Email: "twins@example.com", FirstName: "Third"}, sweap.UpsertKeys(sweap.EXTERNALIDKEY))
+	assert.Nil(t, err)
+	assert.Equal(t, sweap.CREATED, r.Outcome)
+}
+
+func TestUpsertGuests(t *testing.T) {
+	srv := sweaptest.NewServer(upsertSeed())
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	results := api.UpsertGuests(sweap.Guests{
+		{EventID: "event-1", ExternalID: "crm-1", FirstName: "Anna", Email: "anna@example.com"},
+		{EventID: "event-1", ExternalID: "crm-2", FirstName: "Benjamin", Email: "ben@example.com"},
+		{EventID: "event-1", ExternalID: "crm-5", FirstName: "Eve"},
+		{EventID: "event-1", ExternalID: "crm-5", FirstName: "Eve", LastName: "Evans"},
+		{EventID: "event-1", Email: "twins@example.com"},
+		{FirstName: "No event"},
+	})
+
+	outcomes := []sweap.UpsertOutcome{}
+	for _, r := range results {
+		outcomes = append(outcomes, r.Outcome)
+	}
+	assert.Equal(t, []sweap.UpsertOutcome{sweap.UNCHANGED, sweap.UPDATED, sweap.CREATED, sweap.UPDATED, sweap.FAILED, sweap.FAILED}, outcomes)
+	assert.Equal(t, results[2].Guest.ID, results[3].Guest.ID)
+	assert.NotNil(t, results[4].Err)
+	assert.Equal(t, "No event", results[5].Guest.FirstName)
+
+	ben, _ := srv.Guest("g-2")
+	assert.Equal(t, "Benjamin", ben.FirstName)
+	assert.Equal(t, "crm-2", ben.ExternalID)
+	assert.Len(t, srv.Guests("event-1"), 5)
+
+	searches := 0
+	for _, r := range srv.Requests() {
+		if r.Method == "GET" && r.Query.Get("externalId") != "" {
+			searches++
+		}
+	}
+	assert.Equal(t, 0, searches)
+}
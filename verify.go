@@ -0,0 +1,258 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type VerifyStatus string
+
+const (
+	IMPORTED   VerifyStatus = "IMPORTED"
+	MISSING    VerifyStatus = "MISSING"
+	DUPLICATED VerifyStatus = "DUPLICATED"
+	MISMATCHED VerifyStatus = "MISMATCHED"
+)
+
+// FieldMismatch is a field of an imported guest that differs from the submitted guest.
+type FieldMismatch struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// VerifyRow is the verification result of a single submitted guest.
+type VerifyRow struct {
+	Row        int             `json:"row"` // position in the submitted guests, starting with 1
+	Status     VerifyStatus    `json:"status"`
+	MatchedBy  string          `json:"matchedBy,omitempty"` // externalId, email or name
+	GuestIDs   []string        `json:"guestIds,omitempty"`
+	Mismatches []FieldMismatch `json:"mismatches,omitempty"`
+	Submitted  Guest           `json:"submitted"`
+}
+
+// VerifyReport tells which submitted guests have been imported into an event.
+type VerifyReport struct {
+	EventID    string      `json:"eventId"`
+	Imported   int         `json:"imported"`
+	Missing    int         `json:"missing"`
+	Duplicated int         `json:"duplicated"`
+	Mismatched int         `json:"mismatched"`
+	Rows       []VerifyRow `json:"rows"`
+}
+
+// VerifyImport checks which of the submitted guests exist in the event, e.g. after a guest bulk
+// import has finished. Guests are matched by ExternalID, then email, then first and last name,
+// and every guest of the event is matched to one submitted row at most: of two identical rows
+// with one guest in the event, the second is MISSING. A row matching more guests than needed by
+// the rows sharing its key is DUPLICATED.
+// A matched guest is MISMATCHED if a submitted field differs; empty submitted fields are not compared.
+func (api *Client) VerifyImport(ctx context.Context, eventId string, submitted Guests) (*VerifyReport, error) {
+	if eventId == "" {
+		return nil, SweapLibraryError{"no event ID given"}
+	}
+
+	idx := &verifyIndex{keys: map[string]map[string][]int{}}
+	it := api.IterateGuests(ctx, eventId, NewGuestSearchParameters())
+	for it.Next() {
+		idx.add(it.Value())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	// demand counts the rows matching a key value first, so that rows sharing it get a guest each
+	preferred := make([]string, len(submitted))
+	demand := map[string]int{}
+	for i, g := range submitted {
+		for _, key := range verifyKeys {
+			if v := key.value(g); v != "" && len(idx.keys[key.name][v]) > 0 {
+				preferred[i] = key.name + ":" + v
+				demand[preferred[i]]++
+				break
+			}
+		}
+	}
+
+	report := &VerifyReport{EventID: eventId, Rows: make([]VerifyRow, 0, len(submitted))}
+	for i, g := range submitted {
+		demand[preferred[i]]--
+		row := VerifyRow{Row: i + 1, Status: MISSING, Submitted: g}
+		for _, key := range verifyKeys {
+			v := key.value(g)
+			available := idx.available(key.name, v)
+			if v == "" || len(available) == 0 {
+				continue
+			}
+			// matches beyond those needed by the following rows are duplicates
+			take := len(available) - demand[key.name+":"+v]
+			if take < 1 {
+				take = 1
+			}
+			row.MatchedBy = key.name
+			for _, m := range available[:take] {
+				row.GuestIDs = append(row.GuestIDs, idx.guests[m].ID)
+				idx.used[m] = true
+			}
+			if take > 1 {
+				row.Status = DUPLICATED
+				break
+			}
+			row.Mismatches = compareGuest(g, idx.guests[available[0]])
+			row.Status = IMPORTED
+			if len(row.Mismatches) > 0 {
+				row.Status = MISMATCHED
+			}
+			break
+		}
+		report.count(row.Status)
+		report.Rows = append(report.Rows, row)
+	}
+	return report, nil
+}
+
+func (r *VerifyReport) count(s VerifyStatus) {
+	switch s {
+	case IMPORTED:
+		r.Imported++
+	case MISSING:
+		r.Missing++
+	case DUPLICATED:
+		r.Duplicated++
+	case MISMATCHED:
+		r.Mismatched++
+	}
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *VerifyReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV writes a line per submitted guest, with a header line.
+func (r *VerifyReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"row", "status", "matchedBy", "guestIds", "externalId", "email", "firstName", "lastName", "mismatches"})
+	for _, row := range r.Rows {
+		mismatches := make([]string, len(row.Mismatches))
+		for i, m := range row.Mismatches {
+			mismatches[i] = fmt.Sprintf("%v: %q != %q", m.Field, m.Expected, m.Actual)
+		}
+		cw.Write([]string{
+			strconv.Itoa(row.Row), string(row.Status), row.MatchedBy, strings.Join(row.GuestIDs, " "),
+			externalIDString(row.Submitted.ExternalID), row.Submitted.Email, row.Submitted.FirstName, row.Submitted.LastName,
+			strings.Join(mismatches, "; "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type verifyKey struct {
+	name  string
+	value func(Guest) string
+}
+
+var verifyKeys = []verifyKey{
+	{"externalId", func(g Guest) string { return externalIDString(g.ExternalID) }},
+	{"email", func(g Guest) string { return strings.ToLower(g.Email) }},
+	{"name", func(g Guest) string {
+		if g.FirstName == "" && g.LastName == "" {
+			return ""
+		}
+		return strings.ToLower(g.FirstName + "\x00" + g.LastName)
+	}},
+}
+
+// verifyIndex holds the guests of an event by key name and value. Every guest is matched to
+// one submitted row only, so matched guests are marked as used.
+type verifyIndex struct {
+	guests []Guest
+	used   []bool
+	keys   map[string]map[string][]int // positions in guests
+}
+
+func (idx *verifyIndex) add(g Guest) {
+	for _, key := range verifyKeys {
+		v := key.value(g)
+		if v == "" {
+			continue
+		}
+		if idx.keys[key.name] == nil {
+			idx.keys[key.name] = map[string][]int{}
+		}
+		idx.keys[key.name][v] = append(idx.keys[key.name][v], len(idx.guests))
+	}
+	idx.guests = append(idx.guests, g)
+	idx.used = append(idx.used, false)
+}
+
+// available returns the positions of the guests with the key value not matched yet.
+func (idx *verifyIndex) available(key, v string) []int {
+	var positions []int
+	for _, i := range idx.keys[key][v] {
+		if !idx.used[i] {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+// compareGuest returns the fields set in submitted that differ in actual.
+func compareGuest(submitted, actual Guest) []FieldMismatch {
+	var mismatches []FieldMismatch
+	compare := func(field, expected, actual string, equal func(a, b string) bool) {
+		if expected != "" && !equal(expected, actual) {
+			mismatches = append(mismatches, FieldMismatch{Field: field, Expected: expected, Actual: actual})
+		}
+	}
+	exact := func(a, b string) bool { return a == b }
+
+	compare("firstName", submitted.FirstName, actual.FirstName, exact)
+	compare("lastName", submitted.LastName, actual.LastName, exact)
+	compare("email", submitted.Email, actual.Email, strings.EqualFold)
+	if submitted.EntourageCount != 0 {
+		compare("entourageCount", strconv.Itoa(submitted.EntourageCount), strconv.Itoa(actual.EntourageCount), exact)
+	}
+	compare("categoryId", submitted.CategoryID, actual.CategoryID, exact)
+	compare("invitationState", string(submitted.InvitationState), string(actual.InvitationState), exact)
+
+	ids := make([]string, 0, len(submitted.CustomFields))
+	for id := range submitted.CustomFields {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		compare("customFields."+id, submitted.CustomFields[id], actual.CustomFields[id], exact)
+	}
+	return mismatches
+}
@@ -0,0 +1,147 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+	"github.com/theovassiliou/sweap-go/sweaptest"
+)
+
+func TestVerifyImport(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Verify"}},
+		Guests: sweap.Guests{
+			{ID: "g-1", EventID: "event-1", ExternalID: "crm-1", FirstName: "Anna", Email: "anna@example.com",
+				CustomFields: sweap.CustomFields{"title": "Dr."}},
+			{ID: "g-2", EventID: "event-1", FirstName: "Ben", LastName: "Berg", Email: "Ben@Example.com"},
+			{ID: "g-3", EventID: "event-1", FirstName: "Carl", LastName: "Cole"},
+			{ID: "g-4", EventID: "event-1", FirstName: "Cleo", Email: "twins@example.com"},
+			{ID: "g-5", EventID: "event-1", FirstName: "Cora", Email: "twins@example.com"},
+			{ID: "g-6", EventID: "event-2", FirstName: "Dora", Email: "dora@example.com"},
+			{ID: "g-7", EventID: "event-1", ExternalID: "crm-7", FirstName: "Anna", CustomFields: sweap.CustomFields{"title": "Dr."}},
+		},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	submitted := sweap.Guests{
+		{ExternalID: "crm-1", FirstName: "Anna", Email: "ANNA@example.com", CustomFields: sweap.CustomFields{"title": "Dr."}},
+		{FirstName: "Ben", LastName: "Burg", Email: "ben@example.com"},
+		{FirstName: "carl", LastName: "cole"},
+		{FirstName: "Cleo", Email: "twins@example.com"},
+		{FirstName: "Dora", Email: "dora@example.com"},
+		{ExternalID: "crm-7", FirstName: "Anna", CustomFields: sweap.CustomFields{"title": "Prof."}},
+	}
+	report, err := api.VerifyImport(context.Background(), "event-1", submitted)
+	assert.Nil(t, err)
+	assert.Equal(t, "event-1", report.EventID)
+	assert.Equal(t, 1, report.Imported)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 1, report.Duplicated)
+	assert.Equal(t, 3, report.Mismatched)
+	assert.Len(t, report.Rows, 6)
+
+	assert.Equal(t, sweap.VerifyRow{Row: 1, Status: sweap.IMPORTED, MatchedBy: "externalId", GuestIDs: []string{"g-1"}, Submitted: submitted[0]}, report.Rows[0])
+	assert.Equal(t, sweap.MISMATCHED, report.Rows[1].Status)
+	assert.Equal(t, "email", report.Rows[1].MatchedBy)
+	assert.Equal(t, []sweap.FieldMismatch{{Field: "lastName", Expected: "Burg", Actual: "Berg"}}, report.Rows[1].Mismatches)
+	// names are matched case-insensitively, but compared exactly
+	assert.Equal(t, "name", report.Rows[2].MatchedBy)
+	assert.Equal(t, []string{"g-3"}, report.Rows[2].GuestIDs)
+	assert.Equal(t, []sweap.FieldMismatch{
+		{Field: "firstName", Expected: "carl", Actual: "Carl"},
+		{Field: "lastName", Expected: "cole", Actual: "Cole"},
+	}, report.Rows[2].Mismatches)
+	assert.Equal(t, sweap.DUPLICATED, report.Rows[3].Status)
+	assert.Equal(t, []string{"g-4", "g-5"}, report.Rows[3].GuestIDs)
+	assert.Equal(t, sweap.MISSING, report.Rows[4].Status, "guests of other events do not count")
+	assert.Empty(t, report.Rows[4].GuestIDs)
+	assert.Equal(t, []sweap.FieldMismatch{{Field: "customFields.title", Expected: "Prof.", Actual: "Dr."}}, report.Rows[5].Mismatches)
+
+	_, err = api.VerifyImport(context.Background(), "", submitted)
+	assert.ErrorAs(t, err, &sweap.SweapLibraryError{})
+}
+
+func TestVerifyImportMatchesOnce(t *testing.T) {
+	srv := sweaptest.NewServer(sweaptest.Seed{
+		Events: sweap.Events{{ID: "event-1", Name: "Verify"}},
+		Guests: sweap.Guests{
+			{ID: "g-1", EventID: "event-1", FirstName: "John", LastName: "Smith"},
+			{ID: "g-2", EventID: "event-1", FirstName: "Jane", LastName: "Doe"},
+			{ID: "g-3", EventID: "event-1", FirstName: "Jane", LastName: "Doe"},
+			{ID: "g-4", EventID: "event-1", FirstName: "Jane", LastName: "Doe"},
+		},
+	})
+	defer srv.Close()
+	api, _ := srv.Client()
+
+	john := sweap.Guest{FirstName: "John", LastName: "Smith"}
+	jane := sweap.Guest{FirstName: "Jane", LastName: "Doe"}
+	report, err := api.VerifyImport(context.Background(), "event-1", sweap.Guests{john, john, jane, jane})
+	assert.Nil(t, err)
+	statuses := []sweap.VerifyStatus{}
+	for _, r := range report.Rows {
+		statuses = append(statuses, r.Status)
+	}
+	// one John Smith for two rows, three Jane Does for two rows
+	assert.Equal(t, []sweap.VerifyStatus{sweap.IMPORTED, sweap.MISSING, sweap.DUPLICATED, sweap.IMPORTED}, statuses)
+	assert.Equal(t, 2, report.Imported)
+	assert.Equal(t, 1, report.Missing)
+	assert.Equal(t, 1, report.Duplicated)
+	assert.Equal(t, []string{"g-2", "g-3"}, report.Rows[2].GuestIDs)
+	assert.Equal(t, []string{"g-4"}, report.Rows[3].GuestIDs)
+}
+
+func TestVerifyReportWrite(t *testing.T) {
+	report := &sweap.VerifyReport{EventID: "event-1", Imported: 1, Mismatched: 1, Rows: []sweap.VerifyRow{
+		{Row: 1, Status: sweap.IMPORTED, MatchedBy: "externalId", GuestIDs: []string{"g-1"},
+			Submitted: sweap.Guest{ExternalID: 7.0, FirstName: "Anna"}},
+		{Row: 2, Status: sweap.MISMATCHED, MatchedBy: "email", GuestIDs: []string{"g-2"},
+			Mismatches: []sweap.FieldMismatch{{Field: "lastName", Expected: "Burg", Actual: "Berg"}},
+			Submitted:  sweap.Guest{FirstName: "Ben", LastName: "Burg", Email: "ben@example.com"}},
+	}}
+
+	var buf bytes.Buffer
+	assert.Nil(t, report.WriteCSV(&buf))
+	records, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"row", "status", "matchedBy", "guestIds", "externalId", "email", "firstName", "lastName", "mismatches"},
+		{"1", "IMPORTED", "externalId", "g-1", "7", "", "Anna", "", ""},
+		{"2", "MISMATCHED", "email", "g-2", "", "ben@example.com", "Ben", "Burg", `lastName: "Burg" != "Berg"`},
+	}, records)
+
+	buf.Reset()
+	assert.Nil(t, report.WriteJSON(&buf))
+	var decoded sweap.VerifyReport
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, 1, decoded.Mismatched)
+	assert.Equal(t, "Berg", decoded.Rows[1].Mismatches[0].Actual)
+}
@@ -0,0 +1,199 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// WebhookHandlerFunc processes a guest update received by a WebhookHandler.
+// If it returns an error the request is answered with 500, so that the sender delivers it again.
+type WebhookHandlerFunc func(ctx context.Context, u *GuestUpdate) error
+
+type WebhookHandlerOptions func(*WebhookHandler)
+
+// WebhookTolerance sets how far the timestamp of a request may deviate from now. Defaults to 5 minutes.
+func WebhookTolerance(d time.Duration) func(*WebhookHandler) {
+	return func(h *WebhookHandler) {
+		h.tolerance = d
+	}
+}
+
+// WebhookDedupSize sets how many delivery IDs are remembered to detect redeliveries. Defaults to 1024.
+func WebhookDedupSize(n int) func(*WebhookHandler) {
+	return func(h *WebhookHandler) {
+		h.seen = newDeliverySet(n)
+	}
+}
+
+const maxWebhookBodySize = 1 << 20
+
+// WebhookHandler is a http.Handler receiving webhook requests, as sent by a Dispatcher.
+// It verifies the signature and timestamp of each request, decodes it into the
+// GuestUpdate also produced by Listen and skips deliveries it has already processed.
+//
+// Only the format of the Dispatcher is understood: a WebhookPayload signed with SignPayload.
+// Sweap does not document push notifications of its own, so changes of the Sweap API are
+// received by running Listen with a Dispatcher posting to this handler.
+type WebhookHandler struct {
+	secret    string
+	handle    WebhookHandlerFunc
+	tolerance time.Duration
+	seen      *deliverySet
+	now       func() time.Time
+}
+
+// NewWebhookHandler creates a WebhookHandler verifying requests with secret and passing them to handle.
+func NewWebhookHandler(secret string, handle WebhookHandlerFunc, options ...WebhookHandlerOptions) *WebhookHandler {
+	h := &WebhookHandler{
+		secret:    secret,
+		handle:    handle,
+		tolerance: 5 * time.Minute,
+		seen:      newDeliverySet(1024),
+		now:       time.Now,
+	}
+	for _, opt := range options {
+		opt(h)
+	}
+	return h
+}
+
+func (h *WebhookHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize+1))
+	if err != nil {
+		http.Error(rw, "cannot read body", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxWebhookBodySize {
+		http.Error(rw, "body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	ts, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		http.Error(rw, "missing or invalid "+TimestampHeader, http.StatusBadRequest)
+		return
+	}
+	if skew := h.now().Sub(time.Unix(ts, 0)); skew > h.tolerance || skew < -h.tolerance {
+		http.Error(rw, "timestamp outside tolerance", http.StatusUnauthorized)
+		return
+	}
+	if !VerifySignature(h.secret, ts, body, r.Header.Get(SignatureHeader)) {
+		http.Error(rw, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	var p WebhookPayload
+	if err := json.Unmarshal(body, &p); err != nil || p.ID == "" || p.EventType == "" {
+		http.Error(rw, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	switch h.seen.begin(p.ID) {
+	case deliveryDone:
+		rw.WriteHeader(http.StatusOK)
+		return
+	case deliveryInFlight:
+		// the outcome of the first attempt is not known yet, the sender has to try again
+		rw.Header().Set("Retry-After", "1")
+		http.Error(rw, "delivery is being processed", http.StatusServiceUnavailable)
+		return
+	}
+	// a panic counts as a failed delivery, so that a redelivery is processed again
+	processed := false
+	defer func() { h.seen.finish(p.ID, processed) }()
+	err = h.handle(r.Context(), &GuestUpdate{EventType: p.EventType, Value: p.Guest})
+	processed = err == nil
+	if err != nil {
+		http.Error(rw, "processing failed", http.StatusInternalServerError)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+type deliveryState int
+
+const (
+	deliveryNew deliveryState = iota
+	deliveryInFlight
+	deliveryDone
+)
+
+// deliverySet tracks the deliveries being processed and remembers the most recent
+// processed delivery IDs up to a fixed size.
+type deliverySet struct {
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+	done     map[string]struct{}
+	order    []string // ring of the IDs in done
+	next     int
+}
+
+func newDeliverySet(size int) *deliverySet {
+	if size < 1 {
+		size = 1
+	}
+	return &deliverySet{inFlight: map[string]struct{}{}, done: make(map[string]struct{}, size), order: make([]string, size)}
+}
+
+// begin reports the state of a delivery and marks a new one as in flight.
+func (s *deliverySet) begin(id string) deliveryState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.done[id]; ok {
+		return deliveryDone
+	}
+	if _, ok := s.inFlight[id]; ok {
+		return deliveryInFlight
+	}
+	s.inFlight[id] = struct{}{}
+	return deliveryNew
+}
+
+// finish ends a delivery begun before. A processed delivery is remembered, the oldest
+// one is forgotten if the set is full. A failed delivery may be begun again.
+func (s *deliverySet) finish(id string, processed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.inFlight, id)
+	if !processed {
+		return
+	}
+	delete(s.done, s.order[s.next])
+	s.order[s.next] = id
+	s.next = (s.next + 1) % len(s.order)
+	s.done[id] = struct{}{}
+}
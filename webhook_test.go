@@ -0,0 +1,201 @@
+/*
+ Copyright (c) 2023 Theofanis Vassiliou-Gioles
+
+ Permission is hereby granted, free of charge, to any person obtaining a copy of
+ this software and associated documentation files (the "Software"), to deal in
+ the Software without restriction, including without limitation the rights to
+ use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+ the Software, and to permit persons to whom the Software is furnished to do so,
+ subject to the following conditions:
+
+ The above copyright notice and this permission notice shall be included in all
+ copies or substantial portions of the Software.
+
+ THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+ IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+ FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+ COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+ IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+ CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+*/
+
+package sweap_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/theovassiliou/sweap-go"
+)
+
+const samplePayload = `{"id":"delivery-1","eventType":"UPDATE_GUEST","timestamp":"2023-06-01T10:00:00Z","guest":{"id":"guest-1","eventId":"event-1","firstName":"Sven"}}`
+
+func signedRequest(secret string, ts time.Time, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set(sweap.TimestampHeader, strconv.FormatInt(ts.Unix(), 10))
+	req.Header.Set(sweap.SignatureHeader, sweap.SignPayload(secret, ts.Unix(), []byte(body)))
+	return req
+}
+
+func serve(h http.Handler, req *http.Request) int {
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	return rw.Code
+}
+
+func TestWebhookHandler(t *testing.T) {
+	var received []*sweap.GuestUpdate
+	h := sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		received = append(received, u)
+		return nil
+	})
+
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Len(t, received, 1)
+	assert.Equal(t, sweap.UPDATEGUEST, received[0].EventType)
+	assert.Equal(t, "Sven", received[0].Value.(sweap.Guest).FirstName)
+
+	// a redelivery is acknowledged but not processed again
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now().Add(time.Second), samplePayload)))
+	assert.Len(t, received, 1)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"wrong secret", signedRequest("other", time.Now(), samplePayload), http.StatusUnauthorized},
+		{"stale timestamp", signedRequest("secret", time.Now().Add(-time.Hour), samplePayload), http.StatusUnauthorized},
+		{"invalid payload", signedRequest("secret", time.Now(), `{"eventType":"NEW_GUEST"}`), http.StatusBadRequest},
+		{"method", httptest.NewRequest(http.MethodGet, "/webhook", nil), http.StatusMethodNotAllowed},
+		{"no timestamp", httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(samplePayload)), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, serve(h, tt.req))
+		})
+	}
+
+	tampered := signedRequest("secret", time.Now(), samplePayload)
+	tampered.Body = http.NoBody
+	assert.Equal(t, http.StatusUnauthorized, serve(h, tampered))
+	assert.Len(t, received, 1)
+}
+
+func TestWebhookHandlerError(t *testing.T) {
+	calls := 0
+	h := sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	}, sweap.WebhookDedupSize(1))
+
+	assert.Equal(t, http.StatusInternalServerError, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, 2, calls)
+
+	// with a single remembered ID another delivery evicts the first one
+	other := strings.Replace(samplePayload, "delivery-1", "delivery-2", 1)
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), other)))
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, 4, calls)
+}
+
+func TestWebhookHandlerFailedDeliveryIsNotRemembered(t *testing.T) {
+	calls := 0
+	h := sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		calls++
+		if calls == 1 {
+			return errors.New("database down")
+		}
+		return nil
+	}, sweap.WebhookDedupSize(2))
+
+	other := strings.Replace(samplePayload, "delivery-1", "delivery-2", 1)
+	assert.Equal(t, http.StatusInternalServerError, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), other)))
+	// both processed deliveries fit into the set, the failed attempt took no place
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, 3, calls)
+}
+
+func TestWebhookHandlerPanic(t *testing.T) {
+	calls := 0
+	h := sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		calls++
+		if calls == 1 {
+			panic("bug in handler")
+		}
+		return nil
+	})
+
+	assert.Panics(t, func() { serve(h, signedRequest("secret", time.Now(), samplePayload)) })
+	// the delivery is not left in flight
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookHandlerInFlight(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	calls := 0
+	h := sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+			return errors.New("database down")
+		}
+		return nil
+	})
+
+	first := make(chan int)
+	go func() { first <- serve(h, signedRequest("secret", time.Now(), samplePayload)) }()
+	<-started
+
+	// a redelivery while the first attempt is processed is not acknowledged
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, signedRequest("secret", time.Now(), samplePayload))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+	assert.Equal(t, "1", rw.Header().Get("Retry-After"))
+
+	close(release)
+	assert.Equal(t, http.StatusInternalServerError, <-first)
+	assert.Equal(t, http.StatusOK, serve(h, signedRequest("secret", time.Now(), samplePayload)))
+	assert.Equal(t, 2, calls)
+}
+
+func TestWebhookFromDispatcher(t *testing.T) {
+	received := make(chan *sweap.GuestUpdate, 2)
+	srv := httptest.NewServer(sweap.NewWebhookHandler("secret", func(ctx context.Context, u *sweap.GuestUpdate) error {
+		received <- u
+		return nil
+	}))
+	defer srv.Close()
+
+	updates := make(chan *sweap.GuestUpdate, 2)
+	updates <- &sweap.GuestUpdate{EventType: sweap.NEWGUEST, Value: sweap.Guest{ID: "guest-1"}}
+	updates <- &sweap.GuestUpdate{EventType: sweap.DELETEDGUEST, Value: sweap.Guest{ID: "guest-2"}}
+	close(updates)
+
+	d := sweap.NewDispatcher([]sweap.WebhookEndpoint{{URL: srv.URL, Secret: "secret"}})
+	assert.Nil(t, d.Run(context.Background(), updates))
+
+	u := nextUpdate(t, received)
+	assert.Equal(t, sweap.NEWGUEST, u.EventType)
+	assert.Equal(t, "guest-1", u.Value.(sweap.Guest).ID)
+	u = nextUpdate(t, received)
+	assert.Equal(t, sweap.DELETEDGUEST, u.EventType)
+	assert.Equal(t, "guest-2", u.Value.(sweap.Guest).ID)
+}